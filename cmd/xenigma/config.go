@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sudo-sturbia/xenigma/v6/pkg/machine"
+)
+
+// runConfig runs the config command.
+func runConfig(args []string) int {
+	fs := newFlagSet("config",
+		"Usage\n"+
+			"  xenigma config migrate [path...]\n"+
+			"  xenigma config help\n"+
+			"\n"+
+			"migrate upgrades config files to the latest config version, rewriting them\n"+
+			"in place. Defaults to ~/.config/xenigma/xenigma.conf if no path is given.\n"+
+			"\n"+
+			"help prints a guide explaining xenigma.conf.\n",
+	)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	switch fs.Arg(0) {
+	case "migrate":
+		return migrateConfigs(fs.Args()[1:])
+	case "help":
+		configUsage()
+		return exitOK
+	case "":
+		fs.Usage()
+		return exitUsage
+	}
+	return usageError("unknown config command %q, expected migrate or help", fs.Arg(0))
+}
+
+// migrateConfigs upgrades the config files at the given paths to the latest
+// config version, and returns the exit code.
+func migrateConfigs(paths []string) int {
+	if len(paths) == 0 {
+		paths = []string{configPath}
+	}
+
+	for _, path := range paths {
+		version, err := machine.Migrate(path)
+		if err != nil {
+			return fail(err)
+		}
+
+		if version == machine.ConfigVersion {
+			fmt.Printf("%s: already at version %d\n", path, version)
+		} else {
+			fmt.Printf("%s: migrated from version %d to %d\n", path, version, machine.ConfigVersion)
+		}
+	}
+	return exitOK
+}
+
+// configUsage prints a help message explaining xenigma.conf's options.
+func configUsage() {
+	fmt.Fprint(os.Stderr,
+		"This help message explains the components of a machine and how to configure them.\n",
+		"xenigma reads configurations from ~/.config/xenigma/xenigma.conf, which is a JSON,\n",
+		"YAML, or TOML representation of a machine. The format is detected from the file's\n",
+		"contents, and all formats use the fields below. Examples use JSON.\n",
+		"\n",
+		"You can run `xenigma generate 3` to generate a config file with 3 rotors, and\n",
+		"examine the file at ~/.config/xenigma/xenigma.conf.\n",
+		"\n",
+		"Fields\n",
+		"  Version\n",
+		"    \"version\": 1 is the version of the config schema. Files without a version\n",
+		"    are version 0, and older versions are upgraded when read. Run\n",
+		"    `xenigma config migrate` to rewrite a file using the latest version.\n",
+		"\n",
+		"  Mode\n",
+		"    \"mode\": \"enigma\" makes the machine behave like a historical Enigma I, M3,\n",
+		"    or M4. An Enigma machine has three rotors, listed starting with the rightmost\n",
+		"    rotor, and each rotor has a \"ring\" setting and \"notches\" at which the rotor\n",
+		"    turns over the rotor to its left. Step and cycle may be omitted. An M4 has a\n",
+		"    fourth rotor, a Greek wheel with \"stationary\": true, which never moves.\n",
+		"\n",
+		"  Stepping\n",
+		"    \"stepping\" is an optional name of the strategy used to move rotors, one of\n",
+		"    \"odometer\" (the default), \"enigma\" (the default in enigma mode), \"typex\",\n",
+		"    and \"keystream\". \"enigma\" and \"typex\" move a rotor when the rotor before\n",
+		"    it is at one of its \"notches\", \"keystream\" moves rotors irregularly.\n",
+		"\n",
+		"  Alphabet\n",
+		"    \"alphabet\" is an optional string containing the characters encrypted by\n",
+		"    the machine, in order. If omitted, the english alphabet is used. An alphabet\n",
+		"    must contain an even number of unique characters. All other components use\n",
+		"    characters of the alphabet, and must have the same size as it.\n",
+		"\n",
+		"  Rotors\n",
+		"    xenigma allows a variable number of rotors. The number of rotors is the size\n",
+		"    of \"rotors\" array.\n",
+		"\n",
+		"    Rotor's fields are: pathways, position, step, and cycle.\n",
+		"\n",
+		"    Pathways are the electric connections between characters. They are represented\n",
+		"    using a map-like array, with an element for each character of the alphabet,\n",
+		"    where an index and a character represent a map pair. Key and value pairs are\n",
+		"    translated into their position in the alphabet. For example, if\n",
+		"    pathways[0]=\"c\", then a is mapped to c. Arrays are chosen over maps for\n",
+		"    pathways because ordering matters. A historical wiring can be used instead\n",
+		"    of pathways by name, e.g. \"wiring\": \"III\". Run `xenigma show -wirings`\n",
+		"    to list available wirings.\n",
+		"\n",
+		"    Position is an integer which represents the current position of the rotor,\n",
+		"    and must be reachable from the starting position (\"a\").\n",
+		"\n",
+		"    Step is the number of positions a rotor jumps when moving one step forward.\n",
+		"    For example, if a rotor with position=\"a\" and step=3 jumps once, the position\n",
+		"    will change to \"d\". The default step is 1.\n",
+		"\n",
+		"    Cycle is the number of steps needed to complete a full cycle, after which the\n",
+		"    following rotor is shifted. For example, if a rotor with cycle=13, then it\n",
+		"    needs to complete 13 steps for the next rotor to move one step. The default\n",
+		"    cycle is the size of the alphabet, 26 for the english alphabet.\n",
+		"\n",
+		"  Reflector\n",
+		"    Reflector is connections map, which must contain all characters in the\n",
+		"    alphabet, and must be symmetric. Symmetry means that if \"a\" is connected to \"b\",\n",
+		"    then \"b\" must also be connected to \"a\". A historical reflector can be\n",
+		"    used by name instead of a connections map, e.g. \"reflector\": \"UKW-B\".\n",
+		"\n",
+		"  Plugboard\n",
+		"    Plugboard is also a connections map similar to reflector. To keep a character\n",
+		"    unconnected/unplugged, connect it to itself. A plugboard can also be given\n",
+		"    as a list of connected pairs, e.g. \"pairs\": \"ab cd ef\", in which case other\n",
+		"    characters are unplugged.\n",
+		"\n",
+		"Run `xenigma help` for other commands.\n",
+	)
+}
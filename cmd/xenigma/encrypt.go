@@ -0,0 +1,391 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sudo-sturbia/xenigma/v6/pkg/machine"
+	"golang.org/x/term"
+)
+
+// runEncrypt runs the encrypt command.
+func runEncrypt(args []string) int {
+	o := new(options)
+	fs := newFlagSet("encrypt",
+		"Usage\n"+
+			"  xenigma encrypt [options] [message...]\n"+
+			"\n"+
+			"Encrypt a message given as arguments and/or read from a file using -read,\n"+
+			"and print the result. Stdin is read if neither is given, or if the message\n"+
+			"is -, and the result is written as it's produced, so it can be used in\n"+
+			"pipelines. Uses ~/.config/xenigma/xenigma.conf, unless another machine\n"+
+			"is selected.\n"+
+			"\n"+
+			"Files are written using -o, which replaces the file only once it's fully\n"+
+			"written. -r encrypts every file under a directory into the same relative\n"+
+			"paths under the -o directory, each file from the same starting state, so\n"+
+			"files can be decrypted independently.\n"+
+			"\n"+
+			"Options\n",
+	)
+	o.sourceFlags(fs)
+	o.generateFlags(fs)
+	o.cryptFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	return o.crypt(fs.Args())
+}
+
+// runDecrypt runs the decrypt command. Machines are reciprocal, so decrypting
+// is encrypting using the machine that encrypted the message, in the same
+// state, which is why decrypt has no options to generate a machine.
+func runDecrypt(args []string) int {
+	o := new(options)
+	fs := newFlagSet("decrypt",
+		"Usage\n"+
+			"  xenigma decrypt [options] [message...]\n"+
+			"\n"+
+			"Decrypt a message given as arguments and/or read from a file using -read,\n"+
+			"and print the result. Stdin is read if neither is given, or if the message\n"+
+			"is -, and the result is written as it's produced, so it can be used in\n"+
+			"pipelines. The machine must be in the state it was in before the message\n"+
+			"was encrypted, which can be given using -state.\n"+
+			"\n"+
+			"Options\n",
+	)
+	o.sourceFlags(fs)
+	o.cryptFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	return o.crypt(fs.Args())
+}
+
+// crypt encrypts the given message and/or the file given using -read, or
+// the files under the directory given using -r, and returns the exit code.
+func (o *options) crypt(args []string) int {
+	switch {
+	case o.binary && len(args) != 0:
+		return usageError("-binary encrypts the file given using -read, or stdin, and can't be used with a message")
+	case o.binary && (o.jobs > 1 || o.keepCase || o.translit):
+		return usageError("-binary encrypts every byte, and can't be used with -jobs, -preserve-case, or -transliterate")
+	case o.recursive != "" && (len(args) != 0 || o.read != ""):
+		return usageError("-r encrypts the files under a directory, and can't be used with a message or -read")
+	case o.recursive != "" && o.out == "" && !o.inPlace:
+		return usageError("-r requires an output directory given using -o, or -in-place")
+	case o.inPlace && o.out != "":
+		return usageError("can't use both -in-place and -o")
+	case o.inPlace && o.recursive == "" && (o.read == "" || o.read == stdinPath || len(args) != 0):
+		return usageError("-in-place requires a file given using -read, or a directory given using -r, and can't be used with a message")
+	case o.chain && o.recursive == "":
+		return usageError("-chain can only be used with -r")
+	}
+
+	c, err := o.newCipher()
+	if err != nil {
+		return fail(err)
+	}
+
+	switch {
+	case o.recursive != "":
+		err = o.cryptTree(c)
+	case o.inPlace:
+		err = cryptFile(c, o.read, o.read)
+	default:
+		err = o.cryptMessage(c, args)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if o.update {
+		return fail(c.save())
+	}
+	return exitOK
+}
+
+// cryptMessage encrypts the given message and/or the file given using -read,
+// and writes the result to stdout, or to the file given using -o.
+func (o *options) cryptMessage(c *cipher, args []string) error {
+	message, closeMessage, err := o.message(args)
+	if err != nil {
+		return err
+	}
+	defer closeMessage()
+
+	if o.out == "" {
+		return c.encrypt(os.Stdout, message)
+	}
+	return writeAtomic(o.out, 0644, func(w io.Writer) error {
+		return c.encrypt(w, message)
+	})
+}
+
+// cipher encrypts messages using either a machine or a byte machine.
+type cipher struct {
+	encrypt  func(dst io.Writer, src io.Reader) error
+	state    func() machine.State
+	setState func(machine.State) error
+	save     func() error
+}
+
+// newCipher creates a machine, or a byte machine if -binary is given, based
+// on command line flags, and sets its state if -state is given.
+func (o *options) newCipher() (*cipher, error) {
+	var c *cipher
+	if o.binary {
+		m, err := o.newByteMachine()
+		if err != nil {
+			return nil, err
+		}
+
+		if o.defaults {
+			m.Rotors().UseDefaults()
+		}
+
+		c = &cipher{
+			encrypt: func(dst io.Writer, src io.Reader) error {
+				_, err := io.Copy(m.NewWriter(dst), src)
+				return err
+			},
+			state:    m.State,
+			setState: m.SetState,
+			save:     func() error { return machine.WriteByteMachine(m, configPath) },
+		}
+	} else {
+		m, err := o.newMachine()
+		if err != nil {
+			return nil, err
+		}
+
+		if o.defaults {
+			m.Rotors().UseDefaults()
+		}
+		m.SetOptions(machine.EncryptOptions{
+			PreserveCase:  o.keepCase,
+			Transliterate: o.translit,
+		})
+
+		c = &cipher{
+			encrypt: func(dst io.Writer, src io.Reader) error {
+				return o.encrypt(m, dst, src)
+			},
+			state:    m.State,
+			setState: m.SetState,
+			save:     func() error { return machine.Write(m, configPath) },
+		}
+	}
+
+	if o.state != "" {
+		var state machine.State
+		if err := state.UnmarshalText([]byte(o.state)); err != nil {
+			return nil, fmt.Errorf("invalid -state: %w", err)
+		}
+		if err := c.setState(state); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// message returns a reader of the message to encrypt, and a function that
+// closes it. The message is the given arguments separated by spaces,
+// followed by the file given using -read, separated by a newline if both
+// are given. Stdin is read if neither is given, and in place of a single
+// argument or a -read path of "-". Files and stdin are streamed rather than
+// read into memory.
+func (o *options) message(args []string) (io.Reader, func(), error) {
+	var parts []io.Reader
+	switch {
+	case len(args) == 1 && args[0] == stdinPath:
+		parts = append(parts, os.Stdin)
+	case len(args) != 0:
+		parts = append(parts, strings.NewReader(strings.Join(args, " ")))
+	}
+
+	if o.read == "" {
+		if len(parts) == 0 {
+			return os.Stdin, func() {}, nil
+		}
+		return parts[0], func() {}, nil
+	}
+
+	file, closeFile, err := input(o.read)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(parts) != 0 {
+		parts = append(parts, strings.NewReader("\n"))
+	}
+	return io.MultiReader(append(parts, file)...), closeFile, nil
+}
+
+// stdinPath is the path, or message, that is read from stdin.
+const stdinPath = "-"
+
+// input opens the file at path for reading, or returns stdin if path is
+// stdinPath, and returns a function that closes the file.
+func input(path string) (io.Reader, func(), error) {
+	if path == stdinPath {
+		return os.Stdin, func() {}, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %s", path, err.Error())
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// encrypt encrypts message using m and writes the result to w. The message
+// is streamed through the machine, unless -jobs is greater than 1 and m uses
+// the odometer stepper, in which case the message is read into memory and
+// encrypted in parallel. Other steppers can't be advanced arithmetically,
+// so they gain nothing from encrypting in parallel.
+func (o *options) encrypt(m *machine.Machine, w io.Writer, message io.Reader) error {
+	if _, ok := m.Stepper().(machine.OdometerStepper); ok && o.jobs > 1 {
+		contents, err := ioutil.ReadAll(message)
+		if err != nil {
+			return fmt.Errorf("failed to read message: %w", err)
+		}
+
+		enc, err := m.EncryptParallel(contents, o.jobs)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(enc)
+		return err
+	}
+
+	encryptor := m.NewWriter(w)
+	if _, err := io.Copy(encryptor, message); err != nil {
+		return err
+	}
+	return encryptor.Close()
+}
+
+// newByteMachine creates a byte machine based on command line flags.
+func (o *options) newByteMachine() (*machine.ByteMachine, error) {
+	if o.generate > 0 && o.generateW > 0 {
+		return nil, fmt.Errorf("can't use both -generate and -gen-w")
+	}
+	if o.pass > 0 {
+		return nil, fmt.Errorf("can't use -passphrase with -binary")
+	}
+	if o.key != "" {
+		return nil, fmt.Errorf("can't use -key with -binary")
+	}
+	if o.generate > 0 {
+		return o.generateByteMachine(o.generate), nil
+	}
+	if o.generateW > 0 {
+		m := o.generateByteMachine(o.generateW)
+		return m, machine.WriteByteMachine(m, configPath)
+	}
+
+	m, err := machine.ReadByteMachine(o.path())
+	if err != nil {
+		if o.genBackup > 0 {
+			return o.generateByteMachine(o.genBackup), nil
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+// generateByteMachine generates a byte machine with the given number of
+// rotors, using crypto/rand if -secure is given.
+func (o *options) generateByteMachine(count int) *machine.ByteMachine {
+	if o.secure {
+		return machine.GenerateByteMachine(count, machine.SecureSource())
+	}
+	return machine.GenerateByteMachine(count, rand.NewSource(time.Now().UnixNano()))
+}
+
+// newMachine creates and a machine based on command line flags.
+func (o *options) newMachine() (*machine.Machine, error) {
+	if o.generate > 0 && o.generateW > 0 {
+		return nil, fmt.Errorf("can't use both -generate and -gen-w")
+	}
+	if o.pass > 0 && (o.generate > 0 || o.generateW > 0) {
+		return nil, fmt.Errorf("can't use -passphrase with -generate or -gen-w")
+	}
+	if o.key != "" && (o.pass > 0 || o.generate > 0 || o.generateW > 0) {
+		return nil, fmt.Errorf("can't use -key with -passphrase, -generate, or -gen-w")
+	}
+	if o.key != "" {
+		return machine.ParseKey(o.key)
+	}
+	if o.pass > 0 {
+		return o.passphraseMachine(o.pass)
+	}
+	if o.generate > 0 {
+		return o.generateMachine(o.generate), nil
+	}
+	if o.generateW > 0 {
+		m := o.generateMachine(o.generateW)
+		return m, machine.Write(m, configPath)
+	}
+
+	m, err := machine.Read(o.path())
+	if err != nil {
+		if o.genBackup > 0 {
+			return o.generateMachine(o.genBackup), nil
+		}
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// generateMachine generates a machine with the given number of rotors,
+// using crypto/rand if -secure is given.
+func (o *options) generateMachine(count int) *machine.Machine {
+	if o.secure {
+		return machine.GenerateSecure(count)
+	}
+	return machine.Generate(count)
+}
+
+// passphraseMachine prompts for a passphrase, and derives a machine with the
+// given number of rotors from it. The passphrase is read from the terminal,
+// so stdin can be used for the message.
+func (o *options) passphraseMachine(count int) (*machine.Machine, error) {
+	tty := os.Stdin
+	if !term.IsTerminal(int(tty.Fd())) {
+		file, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase, no terminal available: %w", err)
+		}
+		defer file.Close()
+		tty = file
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("no passphrase given")
+	}
+
+	return machine.FromPassphrase(string(passphrase), o.salt, count, machine.DefaultKDFParams)
+}
+
+// path returns the path of the machine to load.
+func (o *options) path() string {
+	switch {
+	case o.load != "":
+		return o.load
+	default:
+		return configPath
+	}
+}
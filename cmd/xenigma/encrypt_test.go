@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+// TestCryptStdin tests encrypting messages read from stdin, and that the
+// output is exactly the encrypted message, without a trailing newline.
+func TestCryptStdin(t *testing.T) {
+	for i, test := range []struct {
+		args  []string
+		stdin string
+		want  string
+	}{
+		{args: []string{"encrypt"}, stdin: "Hello, World!", want: "sispr, areko!"},
+		{args: []string{"encrypt", "-"}, stdin: "Hello, World!", want: "sispr, areko!"},
+		{args: []string{"encrypt", "-read", "-"}, stdin: "Hello, World!", want: "sispr, areko!"},
+		{args: []string{"encrypt"}, stdin: "Hello, World!\n", want: "sispr, areko!\n"},
+		{args: []string{"encrypt"}, stdin: "Hello,\nWorld!\n\n", want: "sispr,\nareko!\n\n"},
+		{args: []string{"encrypt"}, stdin: "", want: ""},
+		{args: []string{"encrypt", "-preserve-case"}, stdin: "Hello, World!", want: "Sispr, Areko!"},
+		{args: []string{"encrypt", "-jobs", "2"}, stdin: "Hello, World!", want: "sispr, areko!"},
+		{args: []string{"decrypt"}, stdin: "sispr, areko!", want: "hello, world!"},
+		{args: []string{"decrypt", "-"}, stdin: "sispr, areko!", want: "hello, world!"},
+		{args: []string{"decrypt", "-read", "-"}, stdin: "sispr, areko!", want: "hello, world!"},
+
+		// A message and -read - are separated by a newline.
+		{args: []string{"encrypt", "-read", "-", "Hello,"}, stdin: "World!", want: "sispr,\nareko!"},
+
+		// Stdin isn't read if a message is given.
+		{args: []string{"encrypt", "Hello,", "World!"}, stdin: "Ignored", want: "sispr, areko!"},
+
+		// A message without a command is encrypted, and so is stdin.
+		{args: []string{}, stdin: "Hello, World!", want: "sispr, areko!"},
+		{args: []string{"-"}, stdin: "Hello, World!", want: "sispr, areko!"},
+		{args: []string{"-read", "-"}, stdin: "Hello, World!", want: "sispr, areko!"},
+	} {
+		useConfig(t, "../../test-data/config-1.json")
+		stdout, stderr, code := runWith(t, test.stdin, test.args...)
+		if code != exitOK {
+			t.Errorf("test %d: incorrect exit code, want: %d, got: %d, stderr: %s", i, exitOK, code, stderr)
+		}
+		if stdout != test.want {
+			t.Errorf("test %d: incorrect output, want: %q, got: %q", i, test.want, stdout)
+		}
+	}
+}
+
+// TestCryptJobs tests that encrypting using -jobs produces the same result
+// as encrypting sequentially, whether or not the machine's stepper can be
+// advanced arithmetically.
+func TestCryptJobs(t *testing.T) {
+	for i, path := range []string{
+		"../../test-data/config-1.json",
+		"../../test-data/enigma-config-1.json",
+	} {
+		useConfig(t, path)
+		want, stderr, code := runWith(t, "", "encrypt", "-read", "../../LICENSE")
+		if code != exitOK {
+			t.Fatalf("test %d: encrypt failed with exit code %d: %s", i, code, stderr)
+		}
+
+		got, stderr, code := runWith(t, "", "encrypt", "-jobs", "4", "-read", "../../LICENSE")
+		if code != exitOK {
+			t.Fatalf("test %d: encrypt failed with exit code %d: %s", i, code, stderr)
+		}
+		if got != want {
+			t.Errorf("test %d: incorrect encryption using -jobs", i)
+		}
+	}
+}
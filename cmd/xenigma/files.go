@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cryptTree encrypts every regular file under the directory given using -r,
+// and writes the result to the same relative path under the directory given
+// using -o, or replaces the file if -in-place is given. Files are encrypted
+// in lexical order, each from the starting state of c, unless -chain is
+// given, in which case each file is encrypted from the state the previous
+// file left the machine in. Unless -chain is given, c is left in its
+// starting state, so -update saves the state the files were encrypted from.
+func (o *options) cryptTree(c *cipher) error {
+	root := o.recursive
+	info, err := os.Stat(root)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", root, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+
+	out := o.out
+	if o.inPlace {
+		out = root
+	} else if inside(out, root) {
+		return fmt.Errorf("output directory %s can't be inside input directory %s, use -in-place to replace files", out, root)
+	}
+
+	start := c.state()
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(out, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("failed to write %s: %w", dst, err)
+		}
+
+		if !o.chain {
+			if err := c.setState(start); err != nil {
+				return err
+			}
+		}
+		return cryptFile(c, path, dst)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !o.chain {
+		return c.setState(start)
+	}
+	return nil
+}
+
+// inside returns true if path is dir, or is inside dir.
+func inside(path, dir string) bool {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(absDir, absPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// cryptFile encrypts the file at src using c, and writes the result to dst,
+// which may be the same file.
+func cryptFile(c *cipher, src, dst string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+
+	return writeAtomic(dst, info.Mode().Perm(), func(w io.Writer) error {
+		if err := c.encrypt(w, file); err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", src, err)
+		}
+		return nil
+	})
+}
+
+// writeAtomic calls write with a temporary file in the directory of path,
+// and renames the file to path once it's written, so path is either left
+// unchanged or fully written. The file has the permissions of the file it
+// replaces, or perm if path doesn't exist.
+func writeAtomic(path string, perm os.FileMode, write func(w io.Writer) error) (err error) {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// file is the contents and permissions of a file in a tree.
+type file struct {
+	contents string
+	perm     os.FileMode
+}
+
+// tree is a directory tree used by tests, mapping relative paths to files.
+var tree = map[string]file{
+	"a.txt":       {"Hello, World!", 0600},
+	"sub/b.txt":   {"Hello, World!", 0644},
+	"sub/c/d.txt": {"Attack at dawn.\n", 0640},
+}
+
+// writeTree writes files under dir.
+func writeTree(t *testing.T, dir string, files map[string]file) {
+	t.Helper()
+
+	for rel, f := range files {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, []byte(f.contents), f.perm); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+		if err := os.Chmod(path, f.perm); err != nil {
+			t.Fatalf("failed to chmod %s: %v", path, err)
+		}
+	}
+}
+
+// readTree reads every file under dir.
+func readTree(t *testing.T, dir string) map[string]file {
+	t.Helper()
+
+	files := make(map[string]file)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = file{string(contents), info.Mode().Perm()}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", dir, err)
+	}
+	return files
+}
+
+// compareTrees reports the differences between the files want and got.
+func compareTrees(t *testing.T, name string, want, got map[string]file) {
+	t.Helper()
+
+	if len(want) != len(got) {
+		t.Errorf("%s: incorrect number of files, want: %d, got: %d", name, len(want), len(got))
+	}
+	for rel, w := range want {
+		g, ok := got[rel]
+		switch {
+		case !ok:
+			t.Errorf("%s: missing %s", name, rel)
+		case g.contents != w.contents:
+			t.Errorf("%s: incorrect contents of %s, want: %q, got: %q", name, rel, w.contents, g.contents)
+		case g.perm != w.perm:
+			t.Errorf("%s: incorrect permissions of %s, want: %v, got: %v", name, rel, w.perm, g.perm)
+		}
+	}
+}
+
+// TestCryptTree tests encrypting and decrypting a directory tree, into
+// another directory or in place.
+func TestCryptTree(t *testing.T) {
+	for i, test := range []struct {
+		flags   []string
+		inPlace bool
+		first   string // Encryption of a.txt, if not empty.
+		chained bool   // Whether sub/b.txt is encrypted from the state a.txt left.
+	}{
+		{first: "sispr, areko!"},
+		{flags: []string{"-chain"}, first: "sispr, areko!", chained: true},
+		{flags: []string{"-state", "3:3,0:0,25:25"}},
+		{flags: []string{"-state", "3:3,0:0,25:25", "-chain"}, chained: true},
+		{inPlace: true, first: "sispr, areko!"},
+		{flags: []string{"-chain"}, inPlace: true, first: "sispr, areko!", chained: true},
+	} {
+		useConfig(t, "../../test-data/config-1.json")
+
+		src := t.TempDir()
+		writeTree(t, src, tree)
+
+		enc, dec := filepath.Join(t.TempDir(), "enc"), filepath.Join(t.TempDir(), "dec")
+		encArgs := append([]string{"encrypt", "-r", src, "-o", enc}, test.flags...)
+		decArgs := append([]string{"decrypt", "-r", enc, "-o", dec}, test.flags...)
+		if test.inPlace {
+			enc, dec = src, src
+			encArgs = append([]string{"encrypt", "-r", src, "-in-place"}, test.flags...)
+			decArgs = append([]string{"decrypt", "-r", src, "-in-place"}, test.flags...)
+		}
+
+		if _, stderr, code := runWith(t, "", encArgs...); code != exitOK {
+			t.Fatalf("test %d: encrypt failed with exit code %d: %s", i, code, stderr)
+		}
+		encrypted := readTree(t, enc)
+		for rel, f := range tree {
+			if encrypted[rel].perm != f.perm {
+				t.Errorf("test %d: incorrect permissions of encrypted %s, want: %v, got: %v", i, rel, f.perm, encrypted[rel].perm)
+			}
+		}
+		if test.first != "" && encrypted["a.txt"].contents != test.first {
+			t.Errorf("test %d: incorrect encryption of a.txt, want: %q, got: %q", i, test.first, encrypted["a.txt"].contents)
+		}
+		if chained := encrypted["a.txt"].contents != encrypted["sub/b.txt"].contents; chained != test.chained {
+			t.Errorf("test %d: incorrect chaining, want: %t, got: %t", i, test.chained, chained)
+		}
+
+		if _, stderr, code := runWith(t, "", decArgs...); code != exitOK {
+			t.Fatalf("test %d: decrypt failed with exit code %d: %s", i, code, stderr)
+		}
+		want := make(map[string]file)
+		for rel, f := range tree {
+			want[rel] = file{strings.ToLower(f.contents), f.perm}
+		}
+		compareTrees(t, fmt.Sprintf("test %d", i), want, readTree(t, dec))
+	}
+}
+
+// TestCryptTreeUpdate tests that -update saves the starting state of the
+// machine, unless -chain is given.
+func TestCryptTreeUpdate(t *testing.T) {
+	for i, test := range []struct {
+		flags []string
+		want  string // Encryption of a message after the tree is encrypted.
+	}{
+		{flags: []string{"-update"}, want: "sispr, areko!"},
+		{flags: []string{"-update", "-chain"}},
+	} {
+		useConfig(t, "../../test-data/config-1.json")
+
+		src := t.TempDir()
+		writeTree(t, src, tree)
+		args := append([]string{"encrypt", "-r", src, "-o", filepath.Join(t.TempDir(), "enc")}, test.flags...)
+		if _, stderr, code := runWith(t, "", args...); code != exitOK {
+			t.Fatalf("test %d: encrypt failed with exit code %d: %s", i, code, stderr)
+		}
+
+		got, stderr, code := runWith(t, "", "encrypt", "Hello,", "World!")
+		if code != exitOK {
+			t.Fatalf("test %d: encrypt failed with exit code %d: %s", i, code, stderr)
+		}
+		if test.want != "" && got != test.want {
+			t.Errorf("test %d: incorrect saved state, want encryption: %q, got: %q", i, test.want, got)
+		}
+		if test.want == "" && got == "sispr, areko!" {
+			t.Errorf("test %d: starting state saved, want the state after the last file", i)
+		}
+	}
+}
+
+// TestCryptTreeErrors tests that invalid input and output directories are
+// rejected without writing any files.
+func TestCryptTreeErrors(t *testing.T) {
+	for i, test := range []struct {
+		args   func(src string) []string
+		code   int
+		stderr string
+	}{
+		{
+			args:   func(src string) []string { return []string{"encrypt", "-r", src, "-o", filepath.Join(src, "enc")} },
+			code:   exitError,
+			stderr: "can't be inside input directory",
+		},
+		{
+			args:   func(src string) []string { return []string{"encrypt", "-r", src, "-o", src} },
+			code:   exitError,
+			stderr: "can't be inside input directory",
+		},
+		{
+			args: func(src string) []string {
+				return []string{"encrypt", "-r", filepath.Join(src, "sub", ".."), "-o", src + "/sub/c"}
+			},
+			code:   exitError,
+			stderr: "can't be inside input directory",
+		},
+		{
+			args: func(src string) []string {
+				return []string{"encrypt", "-r", filepath.Join(src, "a.txt"), "-o", src + "-enc"}
+			},
+			code:   exitError,
+			stderr: "is not a directory",
+		},
+		{
+			args: func(src string) []string {
+				return []string{"encrypt", "-r", filepath.Join(src, "missing"), "-o", src + "-enc"}
+			},
+			code:   exitError,
+			stderr: "no such file or directory",
+		},
+		{
+			args:   func(src string) []string { return []string{"encrypt", "-r", src} },
+			code:   exitUsage,
+			stderr: "-r requires an output directory",
+		},
+		{
+			args:   func(src string) []string { return []string{"encrypt", "-r", src, "-in-place", "-o", src + "-enc"} },
+			code:   exitUsage,
+			stderr: "can't use both -in-place and -o",
+		},
+		{
+			args:   func(src string) []string { return []string{"encrypt", "-chain", "Hello"} },
+			code:   exitUsage,
+			stderr: "-chain can only be used with -r",
+		},
+	} {
+		useConfig(t, "../../test-data/config-1.json")
+
+		src := t.TempDir()
+		writeTree(t, src, tree)
+		_, stderr, code := runWith(t, "", test.args(src)...)
+		if code != test.code {
+			t.Errorf("test %d: incorrect exit code, want: %d, got: %d, stderr: %s", i, test.code, code, stderr)
+		}
+		if !strings.Contains(stderr, test.stderr) {
+			t.Errorf("test %d: stderr doesn't contain %q, got: %q", i, test.stderr, stderr)
+		}
+		compareTrees(t, fmt.Sprintf("test %d", i), tree, readTree(t, src))
+	}
+}
+
+// TestInside tests checking whether a path is inside a directory.
+func TestInside(t *testing.T) {
+	for i, test := range []struct {
+		path, dir string
+		want      bool
+	}{
+		{"a", "a", true},
+		{"a/b", "a", true},
+		{"a/b/c", "a", true},
+		{"a/../a/b", "a", true},
+		{"./a/b", "a/", true},
+		{"a", "a/b", false},
+		{"b", "a", false},
+		{"ab", "a", false},
+		{"..a", ".", true},
+		{"../a", ".", false},
+		{"..", ".", false},
+	} {
+		if got := inside(filepath.FromSlash(test.path), filepath.FromSlash(test.dir)); got != test.want {
+			t.Errorf("test %d: inside(%q, %q), want: %t, got: %t", i, test.path, test.dir, test.want, got)
+		}
+	}
+}
+
+// TestWriteAtomic tests that files are either fully written or left
+// unchanged, without leaving temporary files.
+func TestWriteAtomic(t *testing.T) {
+	for i, test := range []struct {
+		existing *file // The file written to, if it exists.
+		write    string
+		err      error
+		want     file
+	}{
+		{existing: nil, write: "new", want: file{"new", 0640}},
+		{existing: &file{"original", 0600}, write: "new", want: file{"new", 0600}},
+		{existing: &file{"original", 0755}, write: "", want: file{"", 0755}},
+		{existing: &file{"original", 0600}, write: "partial", err: errors.New("write failed"), want: file{"original", 0600}},
+	} {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "file.txt")
+		if test.existing != nil {
+			writeTree(t, dir, map[string]file{"file.txt": *test.existing})
+		}
+
+		err := writeAtomic(path, 0640, func(w io.Writer) error {
+			if _, err := io.WriteString(w, test.write); err != nil {
+				return err
+			}
+			return test.err
+		})
+		if !errors.Is(err, test.err) {
+			t.Errorf("test %d: incorrect error, want: %v, got: %v", i, test.err, err)
+		}
+
+		if test.existing == nil && test.err != nil {
+			compareTrees(t, fmt.Sprintf("test %d", i), map[string]file{}, readTree(t, dir))
+		} else {
+			compareTrees(t, fmt.Sprintf("test %d", i), map[string]file{"file.txt": test.want}, readTree(t, dir))
+		}
+	}
+}
+
+// TestCryptFile tests encrypting a file into another file and in place, and
+// that a failed encryption leaves the destination unchanged.
+func TestCryptFile(t *testing.T) {
+	for i, test := range []struct {
+		dst  string
+		fail bool
+		want map[string]file
+	}{
+		{
+			dst:  "enc.txt",
+			want: map[string]file{"src.txt": {"Hello, World!", 0600}, "enc.txt": {"sispr, areko!", 0600}},
+		},
+		{
+			dst:  "src.txt",
+			want: map[string]file{"src.txt": {"sispr, areko!", 0600}},
+		},
+		{
+			dst:  "src.txt",
+			fail: true,
+			want: map[string]file{"src.txt": {"Hello, World!", 0600}},
+		},
+		{
+			dst:  "enc.txt",
+			fail: true,
+			want: map[string]file{"src.txt": {"Hello, World!", 0600}},
+		},
+	} {
+		o := &options{load: "../../test-data/config-1.json", jobs: 1}
+		c, err := o.newCipher()
+		if err != nil {
+			t.Fatalf("test %d: failed to create cipher: %v", i, err)
+		}
+		if test.fail {
+			encrypt := c.encrypt
+			c.encrypt = func(dst io.Writer, src io.Reader) error {
+				if err := encrypt(dst, io.LimitReader(src, 5)); err != nil {
+					return err
+				}
+				return errors.New("encryption failed")
+			}
+		}
+
+		dir := t.TempDir()
+		writeTree(t, dir, map[string]file{"src.txt": {"Hello, World!", 0600}})
+		err = cryptFile(c, filepath.Join(dir, "src.txt"), filepath.Join(dir, test.dst))
+		if (err != nil) != test.fail {
+			t.Errorf("test %d: incorrect error, want failure: %t, got: %v", i, test.fail, err)
+		}
+		compareTrees(t, fmt.Sprintf("test %d", i), test.want, readTree(t, dir))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sudo-sturbia/xenigma/v6/pkg/machine"
+)
+
+// runGenerate runs the generate command.
+func runGenerate(args []string) int {
+	o := new(options)
+	fs := newFlagSet("generate",
+		"Usage\n"+
+			"  xenigma generate [options] <count>\n"+
+			"\n"+
+			"Generate a machine with given number of rotors, and write it to\n"+
+			"~/.config/xenigma/xenigma.conf, or the path given using -o, replacing\n"+
+			"any existing machine.\n"+
+			"\n"+
+			"Options\n",
+	)
+	out := fs.String("o", configPath, "write the machine to `path`")
+	fs.BoolVar(&o.secure, "secure", false, "use crypto/rand when generating the machine")
+	fs.BoolVar(&o.binary, "binary", false, "generate a byte machine, which encrypts every byte")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if fs.NArg() != 1 {
+		return usageError("generate takes a single number of rotors, got %d arguments", fs.NArg())
+	}
+	count, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || count <= 0 {
+		return usageError("invalid number of rotors %q, expected a positive number", fs.Arg(0))
+	}
+
+	if o.binary {
+		err = machine.WriteByteMachine(o.generateByteMachine(count), *out)
+	} else {
+		err = machine.Write(o.generateMachine(count), *out)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	fmt.Printf("%s: generated a machine with %d rotors\n", *out, count)
+	return exitOK
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// replacements are the commands replacing deprecated flags. Flags that
+// aren't listed are replaced by encrypt with the same flag.
+var replacements = map[string]string{
+	"config-h":  "xenigma config help",
+	"verify":    "xenigma verify",
+	"format":    "xenigma verify -format",
+	"wirings":   "xenigma show -wirings",
+	"print-key": "xenigma show",
+	"gen-w":     "xenigma generate",
+}
+
+// runLegacy runs the deprecated flags of versions without commands, e.g.
+// `xenigma -gen-w 3 <message>`, printing a warning for each flag used.
+// Arguments without flags are encrypted.
+func runLegacy(args []string) int {
+	o := new(options)
+	fs := flag.NewFlagSet("xenigma", flag.ContinueOnError)
+	fs.Usage = usage
+	o.sourceFlags(fs)
+	o.generateFlags(fs)
+	o.cryptFlags(fs)
+	fs.IntVar(&o.generateW, "gen-w", 0, "generate a machine with n rotors and save it")
+	config := fs.Bool("config-h", false, "print configuration help message")
+	verify := fs.String("verify", "", "verifies the correctness of a machine")
+	format := fs.String("format", "text", "output format of -verify, text or json")
+	wirings := fs.Bool("wirings", false, "list historical rotor and reflector wirings")
+	printKey := fs.Bool("print-key", false, "print the key string of the machine and exit")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		replacement, ok := replacements[f.Name]
+		if !ok {
+			replacement = "xenigma encrypt -" + f.Name
+		}
+		fmt.Fprintf(os.Stderr, "Warning: -%s is deprecated, use `%s` instead\n", f.Name, replacement)
+	})
+
+	actions := 0
+	for _, set := range []bool{*config, *verify != "", *wirings, *printKey} {
+		if set {
+			actions++
+		}
+	}
+	if actions > 1 {
+		return usageError("can only use one of -config-h, -verify, -wirings, and -print-key")
+	}
+	if *format != "text" && *verify == "" {
+		return usageError("-format can only be used with -verify")
+	}
+	if (*config || *wirings || *printKey) && fs.NArg() != 0 {
+		return usageError("-config-h, -wirings, and -print-key can't be used with a message")
+	}
+
+	switch {
+	case *config:
+		configUsage()
+		return exitOK
+	case *wirings:
+		listWirings()
+		return exitOK
+	case *verify != "":
+		return verifyConfigs(os.Stdout, append([]string{*verify}, fs.Args()...), *format)
+	case *printKey:
+		return o.show()
+	}
+	return o.crypt(fs.Args())
+}
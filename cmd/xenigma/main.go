@@ -1,138 +1,179 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"strings"
 
-	"github.com/sudo-sturbia/xenigma/v5/pkg/machine"
+	"golang.org/x/term"
 )
 
 var configPath = fmt.Sprintf("%s/.config/xenigma/xenigma.conf", os.Getenv("HOME"))
 
-var (
-	config    = flag.Bool("config-h", false, "print configuration help message")
-	verify    = flag.String("verify", "", "verifies the correctness of a machine")
-	generate  = flag.Int("generate", -1, "generate a machine with specified number of rotors")
-	generateW = flag.Int("gen-w", -1, "generate a machine with n rotors and save it")
-	genBackup = flag.Int("gen-backup", -1, "generate a machine with n rotors if default is invalid")
-	load      = flag.String("load", "", "use the machine at given path")
-	read      = flag.String("read", "", "encrypt contents of a file")
-	update    = flag.Bool("update", false, "overwrite machine with new settings after encryption")
-	defaults  = flag.Bool("defaults", false, "use default values for rotor-related fields")
+// Exit codes of xenigma commands.
+const (
+	exitOK    = 0 // Success, or all verified machines are valid.
+	exitError = 1 // Failure, or a verified machine is invalid.
+	exitUsage = 2 // Invalid command line.
 )
 
-func main() {
-	flag.Usage = usage
-	flag.Parse()
-
-	if *config {
-		configUsage()
-	}
+// command is a subcommand of xenigma.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
 
-	if *verify != "" {
-		_, err := machine.Read(*verify)
-		if err != nil {
-			fmt.Printf("INVALID: %s\n", err.Error())
-		} else {
-			fmt.Printf("VALID\n")
-		}
-	} else {
-		m, err := newMachine()
-		if err != nil {
-			exitWith(err)
-		}
+// commands are the subcommands of xenigma, in the order they are listed.
+var commands = []command{
+	{"encrypt", "Encrypt a message, and print the result.", runEncrypt},
+	{"decrypt", "Decrypt a message, and print the result.", runDecrypt},
+	{"generate", "Generate a machine, and write it to a config file.", runGenerate},
+	{"verify", "Verify the correctness of config files.", runVerify},
+	{"show", "Print the key string of a machine, or historical wirings.", runShow},
+	{"config", "Migrate config files, or explain xenigma.conf.", runConfig},
+}
 
-		if *defaults {
-			m.Rotors().UseDefaults()
-		}
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-		message, err := message()
-		if err != nil {
-			exitWith(err)
+// run runs the command given in args, and returns its exit code. Arguments
+// that don't start with a command are parsed using the deprecated flags of
+// earlier versions. Without arguments, stdin is encrypted, unless it's a
+// terminal, in which case help is printed.
+func run(args []string) int {
+	if len(args) == 0 {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			usage()
+			return exitUsage
 		}
+		return runLegacy(args)
+	}
 
-		enc, err := m.Encrypt(message)
-		if err != nil {
-			exitWith(err)
+	if args[0] == "help" {
+		if len(args) == 1 {
+			usage()
+			return exitOK
 		}
-		fmt.Printf("%s", enc)
-
-		if *update {
-			err := machine.Write(m, configPath)
-			if err != nil {
-				exitWith(err)
-			}
+		cmd, ok := lookup(args[1])
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unknown command %q, run `xenigma help` for a list of commands\n", args[1])
+			return exitUsage
 		}
+		return cmd.run([]string{"-h"})
 	}
-}
 
-// message retrieves a user message to encrypt from the command line and/or
-// a file, and returns an error in case if there's no file, or no message is
-// given.
-func message() (string, error) {
-	builder := new(strings.Builder)
-	for _, argument := range flag.Args() {
-		builder.WriteString(argument + " ")
+	if cmd, ok := lookup(args[0]); ok {
+		return cmd.run(args[1:])
 	}
-	builder.WriteByte('\n')
+	return runLegacy(args)
+}
 
-	if *read != "" {
-		file, err := ioutil.ReadFile(*read)
-		if err != nil {
-			return "", fmt.Errorf("failed to read %s: %s", *read, err.Error())
+// lookup returns the command with the given name, and false if there's no
+// such command.
+func lookup(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
 		}
-		builder.Write(file)
 	}
+	return command{}, false
+}
 
-	message := builder.String()
-	if message == "" {
-		return "", fmt.Errorf("no message to encrypt")
-	}
-	return message, nil
+// options are the settings of a command, set using its flags. Each command
+// registers only the flags it uses.
+type options struct {
+	load     string
+	key      string
+	pass     int
+	salt     string
+	defaults bool
+
+	generate  int
+	generateW int
+	genBackup int
+	secure    bool
+
+	read     string
+	update   bool
+	jobs     int
+	keepCase bool
+	translit bool
+	binary   bool
+
+	out       string
+	inPlace   bool
+	recursive string
+	state     string
+	chain     bool
 }
 
-// newMachine creates and a machine based on command line flags.
-func newMachine() (*machine.Machine, error) {
-	if *generate > 0 && *generateW > 0 {
-		return nil, fmt.Errorf("can't use both -generate and -gen-w")
-	}
-	if *generate > 0 {
-		return machine.Generate(*generate), nil
-	}
-	if *generateW > 0 {
-		m := machine.Generate(*generateW)
-		return m, machine.Write(m, configPath)
-	}
+// sourceFlags registers the flags that select the machine to use.
+func (o *options) sourceFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.load, "load", "", "use the machine at `path` instead of ~/.config/xenigma/xenigma.conf")
+	fs.StringVar(&o.key, "key", "", "use the machine described by a `key` string")
+	fs.IntVar(&o.pass, "passphrase", 0, "prompt for a passphrase, and derive a machine with `count` rotors from it")
+	fs.StringVar(&o.salt, "salt", "", "`salt` used with -passphrase")
+	fs.BoolVar(&o.defaults, "defaults", false, "use default values for rotor-related fields")
+}
 
-	path := path()
-	m, err := machine.Read(path)
-	if err != nil {
-		if *genBackup > 0 {
-			return machine.Generate(*genBackup), nil
-		}
-		return nil, err
-	}
+// generateFlags registers the flags that generate a new machine to use.
+func (o *options) generateFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.generate, "generate", 0, "generate a machine with `count` rotors, and use it")
+	fs.IntVar(&o.genBackup, "gen-backup", 0, "generate a machine with `count` rotors if the config file is invalid")
+	fs.BoolVar(&o.secure, "secure", false, "use crypto/rand when generating a machine")
+}
+
+// cryptFlags registers the flags of encryption.
+func (o *options) cryptFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.read, "read", "", "read the message from `path`, or stdin if path is -, after the message given as arguments")
+	fs.BoolVar(&o.update, "update", false, "save the machine to ~/.config/xenigma/xenigma.conf after encryption")
+	fs.IntVar(&o.jobs, "jobs", 1, "encrypt using `count` goroutines, reading the message into memory, if the machine uses the odometer stepper")
+	fs.BoolVar(&o.keepCase, "preserve-case", false, "restore each encrypted letter to the case of the original letter")
+	fs.BoolVar(&o.translit, "transliterate", false, "encrypt non-ASCII letters as their closest ASCII equivalents")
+	fs.BoolVar(&o.binary, "binary", false, "encrypt the file given using -read, or stdin, as binary data using a byte machine")
+	fs.StringVar(&o.out, "o", "", "write the result to `path` instead of stdout, or to directory path with -r")
+	fs.BoolVar(&o.inPlace, "in-place", false, "replace the file given using -read, or the files under -r, with the result")
+	fs.StringVar(&o.recursive, "r", "", "encrypt every file under directory `dir` into the directory given using -o")
+	fs.StringVar(&o.state, "state", "", "start from rotor `state`, position:takenSteps for each rotor, e.g. 3:3,0:0,25:25")
+	fs.BoolVar(&o.chain, "chain", false, "with -r, encrypt each file from the state the previous file left the machine in")
+}
 
-	return m, nil
+// newFlagSet returns a flag set of the named command, which prints help
+// followed by the command's flags on -h.
+func newFlagSet(name, help string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), help)
+		fs.PrintDefaults()
+	}
+	return fs
 }
 
-// path returns the path of the machine to load.
-func path() string {
-	switch {
-	case *load != "":
-		return *load
-	default:
-		return configPath
+// parseError returns the exit code of an error returned when parsing flags,
+// which is exitOK if help was requested.
+func parseError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
 	}
+	return exitUsage
 }
 
-// exitWith prints an error message and exits..
-func exitWith(err error) {
-	fmt.Printf("Error: %s\n", err.Error())
-	os.Exit(1)
+// usageError prints a command line error and returns exitUsage.
+func usageError(format string, args ...interface{}) int {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	return exitUsage
+}
+
+// fail prints err, if any, and returns the matching exit code.
+func fail(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+	return exitError
 }
 
 // usage prints a user help message.
@@ -141,90 +182,43 @@ func usage() {
 		"xenigma is a modified version of the enigma encryption machine.\n",
 		"\n",
 		"Usage\n",
-		"  xenigma [options] <message>\n",
-		"\n",
-		"Options\n",
-		"  -help                Print this help message.\n",
-		"\n",
-		"  -config-h            Print a help guide explaining xenigma.conf.\n",
-		"\n",
-		"  -verify <path>       Verify the correctness of the the machine at\n",
-		"                       path.\n",
-		"\n",
-		"  -generate <count>    Generate a machine with given number of rotors\n",
-		"                       use it for encryption.\n",
-		"\n",
-		"  -gen-w <count>       Generate a machine with given number of rotors,\n",
-		"                       write it to ~/.config/xenigma/xenigma.conf, and\n",
-		"                       use it for encryption.\n",
+		"  xenigma <command> [options] [arguments]\n",
 		"\n",
-		"  -gen-backup <count>  Generate a new machine, if\n",
-		"                       ~/.config/xenigma/xenigma.conf is invalid.\n",
-		"\n",
-		"  -load <path>         Use machine at path instead of\n",
-		"                       ~/.config/xenigma/xenigma.conf.\n",
-		"\n",
-		"  -read <path>         Read message from path. If both -read is invoked and\n",
-		"                       a message is given as argument, both are encrypted\n",
-		"                       and printed seperated by a new line.\n",
-		"\n",
-		"  -update              Save updated machine to ~/.config/xenigma/xenigma.conf\n",
-		"                       before exiting.\n",
-		"\n",
-		"  -defaults            Use default values for rotor-related fields.\n",
-		"                       Default values are \"a\"'s for rotor positions,\n",
-		"                       1 for step size, and 26 for cycle size.\n",
-		"\n",
-		"See github.com/sudo-sturbia/xenigma for source code.\n",
+		"Commands\n",
 	)
-}
-
-// configUsage prints a help message explaining xenigma.conf's options
-// and exits.
-func configUsage() {
-	defer os.Exit(0)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
 	fmt.Fprint(os.Stderr,
-		"This help message explains the components of a machine and how to configure them.\n",
-		"xenigma reads configurations from ~/.config/xenigma/xenigma.conf, which is a JSON\n",
-		"representation of a machine.\n",
-		"\n",
-		"You can run `xenigma -gen-w 3 Hello, World!` to generate a config file with 3 rotors,\n",
-		"and examine the file at ~/.config/xenigma/xenigma.conf.\n",
-		"\n",
-		"Fields\n",
-		"  Rotors\n",
-		"    xenigma allows a variable number of rotors. The number of rotors is the size\n",
-		"    of \"rotors\" array.\n",
-		"\n",
-		"    Rotor's fields are: pathways, position, step, and cycle.\n",
-		"\n",
-		"    Pathways are the electric connections between characters. They are represented\n",
-		"    using a map-like 26 element array where an index and a character represent a\n",
-		"    map pair. Key and value pairs are translated into their position in the english\n",
-		"    alphabet. For example, if pathways[0]=\"c\", then a is mapped to c. Arrays are\n",
-		"    chosen over maps for pathways because ordering matters.\n",
+		"  help       Print help of a command, e.g. `xenigma help encrypt`.\n",
+		"\n",
+		"Machines are read from ~/.config/xenigma/xenigma.conf, unless another\n",
+		"machine is selected using a command's options. Run `xenigma config help`\n",
+		"for a guide explaining xenigma.conf.\n",
+		"\n",
+		"encrypt and decrypt read stdin if no message is given, or if the message\n",
+		"is -, and write the result exactly as transformed, e.g.\n",
+		"  cat message.txt | xenigma encrypt > encrypted.txt\n",
+		"\n",
+		"Exit Codes\n",
+		"  0  Success.\n",
+		"  1  An error occurred, or a verified machine is invalid.\n",
+		"  2  Invalid command line.\n",
+		"\n",
+		"Deprecated Options\n",
+		"  Options given without a command, e.g. `xenigma -gen-w 3 <message>`, are\n",
+		"  deprecated aliases of the commands above, and print a warning. A message\n",
+		"  given without a command is encrypted, unless its first word is the name\n",
+		"  of a command, e.g. `xenigma show me` runs show, so use `xenigma encrypt`.\n",
+		"\n",
+		"  -config-h            xenigma config help\n",
+		"  -verify <path>...    xenigma verify <path>...\n",
+		"  -format <format>     xenigma verify -format <format>\n",
+		"  -wirings             xenigma show -wirings\n",
+		"  -print-key           xenigma show\n",
+		"  -gen-w <count>       xenigma generate <count>, followed by xenigma encrypt\n",
+		"  Other options        xenigma encrypt with the same options\n",
 		"\n",
-		"    Position is an integer which represents the current position of the rotor,\n",
-		"    and must be reachable from the starting position (\"a\").\n",
-		"\n",
-		"    Step is the number of positions a rotor jumps when moving one step forward.\n",
-		"    For example, if a rotor with position=\"a\" and step=3 jumps once, the position\n",
-		"    will change to \"d\". The default step is 1.\n",
-		"\n",
-		"    Cycle is the number of steps needed to complete a full cycle, after which the\n",
-		"    following rotor is shifted. For example, if a rotor with cycle=13, then it\n",
-		"    needs to complete 13 steps for the next rotor to move one step. The default\n",
-		"    cycle is 26.\n",
-		"\n",
-		"  Reflector\n",
-		"    Reflector is connections map, which must contain all characters in the english\n",
-		"    alphabet, and must be symmetric. Symmetry means that if \"a\" is connected to \"b\",\n",
-		"    then \"b\" must also be connected to \"a\".\n",
-		"\n",
-		"  Plugboard\n",
-		"    Plugboard is also a connections map similar to reflector. To keep a character\n",
-		"    unconnected/unplugged, connect it to itself.\n",
-		"\n",
-		"Run `xenigma -h` for other options.\n",
+		"See github.com/sudo-sturbia/xenigma for source code.\n",
 	)
 }
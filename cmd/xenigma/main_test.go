@@ -0,0 +1,264 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useConfig points configPath to a copy of the config file at path in a
+// temporary directory.
+func useConfig(t *testing.T, path string) {
+	t.Helper()
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+
+	old := configPath
+	configPath = filepath.Join(t.TempDir(), "xenigma.conf")
+	t.Cleanup(func() { configPath = old })
+	if err := ioutil.WriteFile(configPath, contents, 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+}
+
+// runWith runs the command given in args with stdin read from a pipe
+// containing the given input, and returns what the command wrote to stdout
+// and stderr, and its exit code.
+func runWith(t *testing.T, stdin string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	defer r.Close()
+	go func() {
+		w.Write([]byte(stdin))
+		w.Close()
+	}()
+
+	dir := t.TempDir()
+	out, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatalf("failed to create stdout: %v", err)
+	}
+	defer out.Close()
+	errs, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatalf("failed to create stderr: %v", err)
+	}
+	defer errs.Close()
+
+	oldStdin, oldStdout, oldStderr := os.Stdin, os.Stdout, os.Stderr
+	os.Stdin, os.Stdout, os.Stderr = r, out, errs
+	code = run(args)
+	os.Stdin, os.Stdout, os.Stderr = oldStdin, oldStdout, oldStderr
+
+	outContents, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatalf("failed to read stdout: %v", err)
+	}
+	errContents, err := ioutil.ReadFile(errs.Name())
+	if err != nil {
+		t.Fatalf("failed to read stderr: %v", err)
+	}
+	return string(outContents), string(errContents), code
+}
+
+// TestRun tests the output and exit codes of commands.
+func TestRun(t *testing.T) {
+	for i, test := range []struct {
+		args   []string
+		code   int
+		stdout string // Exact stdout, if not empty.
+		stderr string // A substring of stderr, if not empty.
+	}{
+		{args: []string{"encrypt", "Hello,", "World!"}, code: exitOK, stdout: "sispr, areko!"},
+		{args: []string{"decrypt", "sispr,", "areko!"}, code: exitOK, stdout: "hello, world!"},
+		{args: []string{"encrypt", "-preserve-case", "Hello,", "World!"}, code: exitOK, stdout: "Sispr, Areko!"},
+		{args: []string{"encrypt", "-load", "missing.json", "Hello"}, code: exitError, stderr: "failed to open missing.json"},
+		{args: []string{"encrypt", "-state", "0:5,0:0,0:0", "Hello"}, code: exitError, stderr: "invalid taken steps: 5, want 0 at position 0"},
+		{args: []string{"encrypt", "-unknown", "Hello"}, code: exitUsage, stderr: "flag provided but not defined: -unknown"},
+		{args: []string{"decrypt", "-generate", "3", "Hello"}, code: exitUsage, stderr: "flag provided but not defined: -generate"},
+		{args: []string{"encrypt", "-h"}, code: exitOK, stderr: "xenigma encrypt [options] [message...]"},
+		{args: []string{"help"}, code: exitOK, stderr: "Commands"},
+		{args: []string{"help", "encrypt"}, code: exitOK, stderr: "xenigma encrypt [options] [message...]"},
+		{args: []string{"help", "verify"}, code: exitOK, stderr: "xenigma verify [options] [path...]"},
+		{args: []string{"help", "unknown"}, code: exitUsage, stderr: `unknown command "unknown"`},
+		{args: []string{"verify", "../../test-data/config-1.json"}, code: exitOK, stdout: "VALID   ../../test-data/config-1.json\n"},
+		{args: []string{"verify", "../../test-data/wrong-config-7.json"}, code: exitError},
+		{args: []string{"verify", "-format", "xml"}, code: exitUsage, stderr: `unknown format "xml"`},
+		{args: []string{"show", "-wirings"}, code: exitOK},
+		{args: []string{"show", "-key", "X1:rotors=III,II,I;pos=aaa;refl=UKW-B"}, code: exitOK, stdout: "X1:rotors=III,II,I;pos=aaa;refl=UKW-B\n"},
+		{args: []string{"generate"}, code: exitUsage, stderr: "generate takes a single number of rotors"},
+		{args: []string{"generate", "none"}, code: exitUsage, stderr: `invalid number of rotors "none"`},
+		{args: []string{"config"}, code: exitUsage, stderr: "xenigma config migrate [path...]"},
+		{args: []string{"config", "help"}, code: exitOK, stderr: "Fields"},
+		{args: []string{"config", "unknown"}, code: exitUsage, stderr: `unknown config command "unknown"`},
+
+		// A message starting with the name of a command runs the command,
+		// which rejects the rest of the message.
+		{args: []string{"show", "me", "the", "way"}, code: exitUsage, stderr: "use `xenigma encrypt <message>`"},
+	} {
+		useConfig(t, "../../test-data/config-1.json")
+		stdout, stderr, code := runWith(t, "", test.args...)
+		if code != test.code {
+			t.Errorf("test %d: incorrect exit code, want: %d, got: %d, stderr: %s", i, test.code, code, stderr)
+		}
+		if test.stdout != "" && stdout != test.stdout {
+			t.Errorf("test %d: incorrect stdout, want: %q, got: %q", i, test.stdout, stdout)
+		}
+		if !strings.Contains(stderr, test.stderr) {
+			t.Errorf("test %d: stderr doesn't contain %q, got: %q", i, test.stderr, stderr)
+		}
+	}
+}
+
+// TestGenerate tests that generate writes a valid machine.
+func TestGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "machine.json")
+	if _, stderr, code := runWith(t, "", "generate", "-o", path, "5"); code != exitOK {
+		t.Fatalf("incorrect exit code, want: %d, got: %d, stderr: %s", exitOK, code, stderr)
+	}
+	if _, _, code := runWith(t, "", "verify", path); code != exitOK {
+		t.Errorf("generated machine is invalid")
+	}
+}
+
+// TestRunLegacy tests that deprecated flags run the commands replacing
+// them, and print a warning naming the replacement.
+func TestRunLegacy(t *testing.T) {
+	for i, test := range []struct {
+		args     []string
+		warnings []string
+		code     int
+		stdout   string // Exact stdout, if not empty.
+	}{
+		{
+			args:     []string{"-config-h"},
+			warnings: []string{"-config-h is deprecated, use `xenigma config help`"},
+			code:     exitOK,
+		},
+		{
+			args:     []string{"-verify", "../../test-data/config-1.json"},
+			warnings: []string{"-verify is deprecated, use `xenigma verify`"},
+			code:     exitOK,
+			stdout:   "VALID   ../../test-data/config-1.json\n",
+		},
+		{
+			args: []string{"-format", "json", "-verify", "../../test-data/wrong-config-7.json"},
+			warnings: []string{
+				"-format is deprecated, use `xenigma verify -format`",
+				"-verify is deprecated, use `xenigma verify`",
+			},
+			code: exitError,
+		},
+		{
+			args:     []string{"-wirings"},
+			warnings: []string{"-wirings is deprecated, use `xenigma show -wirings`"},
+			code:     exitOK,
+		},
+		{
+			args:     []string{"-print-key", "-key", "X1:rotors=III,II,I;pos=aaa;refl=UKW-B"},
+			warnings: []string{"-print-key is deprecated, use `xenigma show`", "-key is deprecated, use `xenigma encrypt -key`"},
+			code:     exitOK,
+			stdout:   "X1:rotors=III,II,I;pos=aaa;refl=UKW-B\n",
+		},
+		{
+			args:     []string{"-gen-w", "3", "Hello"},
+			warnings: []string{"-gen-w is deprecated, use `xenigma generate`"},
+			code:     exitOK,
+		},
+		{
+			args:     []string{"-generate", "3", "-secure", "Hello"},
+			warnings: []string{"-generate is deprecated, use `xenigma encrypt -generate`", "-secure is deprecated, use `xenigma encrypt -secure`"},
+			code:     exitOK,
+		},
+		{
+			args:     []string{"-load", "missing.json", "-gen-backup", "3", "Hello"},
+			warnings: []string{"-load is deprecated, use `xenigma encrypt -load`", "-gen-backup is deprecated, use `xenigma encrypt -gen-backup`"},
+			code:     exitOK,
+		},
+		{
+			args:     []string{"-preserve-case", "-jobs", "2", "Hello,", "World!"},
+			warnings: []string{"-preserve-case is deprecated, use `xenigma encrypt -preserve-case`", "-jobs is deprecated, use `xenigma encrypt -jobs`"},
+			code:     exitOK,
+			stdout:   "Sispr, Areko!",
+		},
+		{
+			args:     []string{"-transliterate", "-defaults", "-salt", "salt", "Héllo"},
+			warnings: []string{"-transliterate is deprecated", "-defaults is deprecated", "-salt is deprecated"},
+			code:     exitOK,
+		},
+		{
+			args:     []string{"-read", "../../LICENSE", "-update"},
+			warnings: []string{"-read is deprecated, use `xenigma encrypt -read`", "-update is deprecated, use `xenigma encrypt -update`"},
+			code:     exitOK,
+		},
+		{
+			args:     []string{"-binary", "-read", "../../LICENSE"},
+			warnings: []string{"-binary is deprecated, use `xenigma encrypt -binary`"},
+			code:     exitError, // The config isn't a byte machine.
+		},
+		{
+			args:   []string{"Hello,", "World!"},
+			code:   exitOK,
+			stdout: "sispr, areko!",
+		},
+		{
+			args:     []string{"-config-h", "-wirings"},
+			warnings: []string{"-config-h is deprecated", "-wirings is deprecated"},
+			code:     exitUsage,
+		},
+		{
+			args:     []string{"-wirings", "Hello"},
+			warnings: []string{"-wirings is deprecated"},
+			code:     exitUsage,
+		},
+		{
+			args:     []string{"-format", "json", "Hello"},
+			warnings: []string{"-format is deprecated"},
+			code:     exitUsage,
+		},
+	} {
+		useConfig(t, "../../test-data/config-1.json")
+		stdout, stderr, code := runWith(t, "", test.args...)
+		if code != test.code {
+			t.Errorf("test %d: incorrect exit code, want: %d, got: %d, stderr: %s", i, test.code, code, stderr)
+		}
+		if test.stdout != "" && stdout != test.stdout {
+			t.Errorf("test %d: incorrect stdout, want: %q, got: %q", i, test.stdout, stdout)
+		}
+		for _, warning := range test.warnings {
+			if !strings.Contains(stderr, "Warning: "+warning) {
+				t.Errorf("test %d: stderr doesn't contain warning %q, got: %q", i, warning, stderr)
+			}
+		}
+		if len(test.warnings) == 0 && strings.Contains(stderr, "Warning") {
+			t.Errorf("test %d: want no warnings, got: %q", i, stderr)
+		}
+	}
+}
+
+// TestReplacements tests that every deprecated flag with a replacement other
+// than encrypt is covered by TestRunLegacy.
+func TestReplacements(t *testing.T) {
+	tested := map[string]bool{
+		"config-h":  true,
+		"verify":    true,
+		"format":    true,
+		"wirings":   true,
+		"print-key": true,
+		"gen-w":     true,
+	}
+	for flag := range replacements {
+		if !tested[flag] {
+			t.Errorf("-%s: replacement isn't tested", flag)
+		}
+	}
+}
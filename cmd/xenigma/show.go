@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sudo-sturbia/xenigma/v6/pkg/machine"
+)
+
+// runShow runs the show command.
+func runShow(args []string) int {
+	o := new(options)
+	fs := newFlagSet("show",
+		"Usage\n"+
+			"  xenigma show [options]\n"+
+			"\n"+
+			"Print the key string of a machine, a single line containing its settings,\n"+
+			"which can be used with -key. Uses ~/.config/xenigma/xenigma.conf, unless\n"+
+			"another machine is selected.\n"+
+			"\n"+
+			"Options\n",
+	)
+	o.sourceFlags(fs)
+	wirings := fs.Bool("wirings", false, "list historical rotor and reflector wirings instead")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	if fs.NArg() != 0 {
+		return usageError("show takes no arguments, got %q, use `xenigma encrypt <message>` to encrypt a message", strings.Join(fs.Args(), " "))
+	}
+	if *wirings {
+		listWirings()
+		return exitOK
+	}
+	return o.show()
+}
+
+// show prints the key string of the machine, and returns the exit code.
+func (o *options) show() int {
+	m, err := o.newMachine()
+	if err != nil {
+		return fail(err)
+	}
+
+	if o.defaults {
+		m.Rotors().UseDefaults()
+	}
+
+	k, err := m.KeyString()
+	if err != nil {
+		return fail(err)
+	}
+	fmt.Println(k)
+	return exitOK
+}
+
+// listWirings prints the names of historical rotor and reflector wirings
+// that can be used in config files.
+func listWirings() {
+	fmt.Println("Rotors")
+	for _, wiring := range machine.HistoricalRotors() {
+		notches := wiring.Notches
+		if notches == "" {
+			notches = "- (stationary)"
+		}
+		fmt.Printf("  %-6s %s  notches: %s\n", wiring.Name, strings.ToUpper(wiring.Letters), strings.ToUpper(notches))
+	}
+
+	fmt.Println("\nReflectors")
+	for _, wiring := range machine.HistoricalReflectors() {
+		fmt.Printf("  %-10s %s\n", wiring.Name, strings.ToUpper(wiring.Letters))
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sudo-sturbia/xenigma/v6/pkg/machine"
+)
+
+// verifyReport is the result of verifying config files, as printed by
+// verify -format json.
+type verifyReport struct {
+	Valid bool         `json:"valid"`
+	Files []verifyFile `json:"files"`
+}
+
+// verifyFile is the result of verifying a single config file.
+type verifyFile struct {
+	File   string          `json:"file"`
+	Valid  bool            `json:"valid"`
+	Errors []verifyProblem `json:"errors,omitempty"`
+}
+
+// verifyProblem is an invalid field of a config file. Line and column are
+// 0 if the position of the field is unknown.
+type verifyProblem struct {
+	Path    string `json:"path"`
+	Reason  string `json:"reason"`
+	Got     string `json:"got,omitempty"`
+	Want    string `json:"want,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// runVerify runs the verify command.
+func runVerify(args []string) int {
+	fs := newFlagSet("verify",
+		"Usage\n"+
+			"  xenigma verify [options] [path...]\n"+
+			"\n"+
+			"Verify the correctness of the machines at given paths or glob patterns,\n"+
+			"e.g. 'configs/*.yaml', or ~/.config/xenigma/xenigma.conf if no path is\n"+
+			"given. Every invalid field is reported with its position in the file.\n"+
+			"Exits with 1 if any machine is invalid, and 2 if the format is unknown.\n"+
+			"\n"+
+			"Options\n",
+	)
+	format := fs.String("format", "text", "output `format`, text or json")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{configPath}
+	}
+	return verifyConfigs(os.Stdout, patterns, *format)
+}
+
+// verifyConfigs verifies the config files matching the given paths or glob
+// patterns, prints a report in the given format, "text" or "json", to w,
+// and returns the exit code.
+func verifyConfigs(w io.Writer, patterns []string, format string) int {
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q, expected text or json\n", format)
+		return exitUsage
+	}
+
+	report := verifyReport{Valid: true}
+	for _, pattern := range patterns {
+		paths, err := filepath.Glob(pattern)
+		if err != nil || len(paths) == 0 {
+			// Not a pattern, or a pattern that matches no files, which is
+			// reported as a missing file.
+			paths = []string{pattern}
+		}
+
+		for _, path := range paths {
+			file := verifyFile{File: path, Valid: true}
+			if _, err := machine.Read(path); err != nil {
+				file.Valid = false
+				file.Errors = problems(err)
+				report.Valid = false
+			}
+			report.Files = append(report.Files, file)
+		}
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fail(fmt.Errorf("failed to write report: %w", err))
+		}
+	} else {
+		for _, file := range report.Files {
+			if file.Valid {
+				fmt.Fprintf(w, "VALID   %s\n", file.File)
+				continue
+			}
+
+			fmt.Fprintf(w, "INVALID %s\n", file.File)
+			for _, problem := range file.Errors {
+				if problem.Line == 0 {
+					fmt.Fprintf(w, "  %s: %s\n", file.File, problem.Message)
+				} else {
+					fmt.Fprintf(w, "  %s:%d:%d: %s\n", file.File, problem.Line, problem.Column, problem.Message)
+				}
+			}
+		}
+	}
+
+	if !report.Valid {
+		return exitError
+	}
+	return exitOK
+}
+
+// problems returns the invalid fields reported by err.
+func problems(err error) []verifyProblem {
+	var errs machine.ValidationErrors
+	if !errors.As(err, &errs) {
+		return []verifyProblem{{Reason: err.Error(), Message: err.Error()}}
+	}
+
+	converted := make([]verifyProblem, len(errs))
+	for i, err := range errs {
+		converted[i] = verifyProblem{
+			Path:    err.Path,
+			Reason:  err.Reason,
+			Got:     err.Got,
+			Want:    err.Want,
+			Line:    err.Line,
+			Column:  err.Column,
+			Message: err.Error(),
+		}
+	}
+	return converted
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestVerifyConfigsText tests the text report and exit code of verifying
+// config files given as paths and glob patterns.
+func TestVerifyConfigsText(t *testing.T) {
+	for i, test := range []struct {
+		patterns []string
+		want     string
+		code     int
+	}{
+		{
+			patterns: []string{"../../test-data/config-1.json"},
+			want:     "VALID   ../../test-data/config-1.json\n",
+			code:     exitOK,
+		},
+		{
+			patterns: []string{"../../test-data/config-1.json", "../../test-data/wrong-config-6.json"},
+			want: "VALID   ../../test-data/config-1.json\n" +
+				"INVALID ../../test-data/wrong-config-6.json\n" +
+				"  ../../test-data/wrong-config-6.json:5:13: rotors[0].position: invalid position (got \"i\", want a multiple of step 3)\n" +
+				"  ../../test-data/wrong-config-6.json:7:13: rotors[0].cycle: cycle and step are incompatible, some collisions may occur (got step 3 and cycle 26, want step times cycle dividing 26)\n",
+			code: exitError,
+		},
+		{
+			patterns: []string{"../../test-data/wrong-config-[17].json"},
+			want: "INVALID ../../test-data/wrong-config-1.json\n" +
+				"  ../../test-data/wrong-config-1.json:1:1: reflector: no reflector connections or name given\n" +
+				"INVALID ../../test-data/wrong-config-7.json\n" +
+				"  ../../test-data/wrong-config-7.json:5:13: rotors[0].position: invalid position (got \"b\", want a multiple of step 2)\n",
+			code: exitError,
+		},
+		{
+			patterns: []string{"../../test-data/config-[12].json"},
+			want:     "VALID   ../../test-data/config-1.json\nVALID   ../../test-data/config-2.json\n",
+			code:     exitOK,
+		},
+		{
+			patterns: []string{"../../test-data/nonexist-*.json"},
+			want: "INVALID ../../test-data/nonexist-*.json\n" +
+				"  ../../test-data/nonexist-*.json: failed to open ../../test-data/nonexist-*.json: no such file or directory\n",
+			code: exitError,
+		},
+	} {
+		buffer := new(bytes.Buffer)
+		if code := verifyConfigs(buffer, test.patterns, "text"); code != test.code {
+			t.Errorf("test %d: incorrect exit code, want: %d, got: %d", i, test.code, code)
+		}
+		if diff := cmp.Diff(test.want, buffer.String()); diff != "" {
+			t.Errorf("test %d: mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestVerifyConfigsJSON tests the schema of the JSON report.
+func TestVerifyConfigsJSON(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	code := verifyConfigs(buffer, []string{"../../test-data/config-1.json", "../../test-data/wrong-config-7.json"}, "json")
+	if code != exitError {
+		t.Errorf("incorrect exit code, want: %d, got: %d", exitError, code)
+	}
+
+	want := `{
+		"valid": false,
+		"files": [
+			{"file": "../../test-data/config-1.json", "valid": true},
+			{
+				"file": "../../test-data/wrong-config-7.json",
+				"valid": false,
+				"errors": [
+					{
+						"path": "rotors[0].position",
+						"reason": "invalid position",
+						"got": "\"b\"",
+						"want": "a multiple of step 2",
+						"line": 5,
+						"column": 13,
+						"message": "rotors[0].position: invalid position (got \"b\", want a multiple of step 2)"
+					}
+				]
+			}
+		]
+	}`
+
+	var wantReport, gotReport interface{}
+	if err := json.Unmarshal([]byte(want), &wantReport); err != nil {
+		t.Fatalf("failed to unmarshal expected report: %v", err)
+	}
+	if err := json.Unmarshal(buffer.Bytes(), &gotReport); err != nil {
+		t.Fatalf("failed to unmarshal report: %v", err)
+	}
+	if diff := cmp.Diff(wantReport, gotReport); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	buffer.Reset()
+	if code := verifyConfigs(buffer, []string{"../../test-data/config-1.json"}, "json"); code != exitOK {
+		t.Errorf("valid config: incorrect exit code, want: %d, got: %d", exitOK, code)
+	}
+	if !strings.HasPrefix(buffer.String(), "{\n  \"valid\": true,") {
+		t.Errorf("valid config: incorrect report: %s", buffer.String())
+	}
+}
+
+// failingWriter is an io.Writer that always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+// TestVerifyConfigsErrors tests exit codes of an unknown format, and of a
+// report that can't be written.
+func TestVerifyConfigsErrors(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	if code := verifyConfigs(buffer, []string{"../../test-data/config-1.json"}, "xml"); code != exitUsage {
+		t.Errorf("unknown format: incorrect exit code, want: %d, got: %d", exitUsage, code)
+	}
+	if buffer.Len() != 0 {
+		t.Errorf("unknown format: want no report, got: %s", buffer.String())
+	}
+
+	if code := verifyConfigs(failingWriter{}, []string{"../../test-data/config-1.json"}, "json"); code != exitError {
+		t.Errorf("failed write: incorrect exit code, want: %d, got: %d", exitError, code)
+	}
+}
@@ -1,5 +1,11 @@
-module github.com/sudo-sturbia/xenigma/v5
+module github.com/sudo-sturbia/xenigma/v6
 
 go 1.16
 
-require github.com/google/go-cmp v0.5.4
+require (
+	github.com/BurntSushi/toml v1.2.1
+	github.com/google/go-cmp v0.5.4
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
+	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d
+	gopkg.in/yaml.v3 v3.0.1
+)
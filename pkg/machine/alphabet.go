@@ -0,0 +1,155 @@
+package machine
+
+import (
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Alphabet is an ordered set of characters encrypted by a machine. Each
+// character is represented in a machine's components by its position in the
+// alphabet. Characters that are not a part of a machine's alphabet are not
+// encrypted.
+//
+// An alphabet must contain an even number of characters, so that generated
+// reflectors and plugboards can connect all characters in pairs.
+type Alphabet struct {
+	letters []rune
+	indices map[rune]int
+}
+
+// Predefined alphabets.
+var (
+	// LatinAlphabet is the english alphabet, and the default alphabet of a
+	// machine.
+	LatinAlphabet = mustAlphabet("abcdefghijklmnopqrstuvwxyz")
+
+	// AlphanumericAlphabet contains digits followed by the english alphabet.
+	AlphanumericAlphabet = mustAlphabet("0123456789abcdefghijklmnopqrstuvwxyz")
+
+	// GermanAlphabet is the english alphabet followed by german umlauts and
+	// eszett.
+	GermanAlphabet = mustAlphabet("abcdefghijklmnopqrstuvwxyzäöüß")
+
+	// PrintableAlphabet contains all printable ASCII characters except for
+	// space.
+	PrintableAlphabet = mustAlphabet(printableASCII())
+)
+
+// NewAlphabet creates and returns a new alphabet containing the characters
+// of letters in order, and an error if letters contains duplicate characters
+// or an odd number of characters.
+func NewAlphabet(letters string) (*Alphabet, error) {
+	if !utf8.ValidString(letters) {
+		return nil, &ValidationError{Reason: "alphabet is not valid UTF-8"}
+	}
+
+	alphabet := &Alphabet{
+		letters: []rune(letters),
+		indices: make(map[rune]int),
+	}
+	var errs ValidationErrors
+	for i, letter := range alphabet.letters {
+		if j, ok := alphabet.indices[letter]; ok {
+			errs = append(errs, &ValidationError{
+				Path:   fmt.Sprintf("[%d]", i),
+				Reason: fmt.Sprintf("duplicate character, also at [%d]", j),
+				Got:    strconv.QuoteRune(letter),
+			})
+			continue
+		}
+		alphabet.indices[letter] = i
+	}
+
+	errs.add("", alphabet.Verify())
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return alphabet, nil
+}
+
+// mustAlphabet is similar to NewAlphabet, but panics if letters are
+// invalid. It's used to initialize predefined alphabets.
+func mustAlphabet(letters string) *Alphabet {
+	alphabet, err := NewAlphabet(letters)
+	if err != nil {
+		panic(err)
+	}
+	return alphabet
+}
+
+// printableASCII returns a string containing all printable ASCII characters
+// except for space.
+func printableASCII() string {
+	printable := make([]byte, 0, '~'-'!'+1)
+	for c := byte('!'); c <= '~'; c++ {
+		printable = append(printable, c)
+	}
+	return string(printable)
+}
+
+// Verify returns an error if alphabet's size is invalid.
+func (a *Alphabet) Verify() error {
+	switch {
+	case len(a.letters) < 2:
+		return &ValidationError{Reason: "alphabet must contain at least 2 characters", Got: strconv.Itoa(len(a.letters))}
+	case len(a.letters)%2 != 0:
+		return &ValidationError{Reason: "alphabet must contain an even number of characters", Got: strconv.Itoa(len(a.letters))}
+	}
+	return nil
+}
+
+// Size returns the number of characters in the alphabet.
+func (a *Alphabet) Size() int {
+	return len(a.letters)
+}
+
+// String returns the characters of the alphabet in order.
+func (a *Alphabet) String() string {
+	return string(a.letters)
+}
+
+// quote returns the character at position i in the alphabet as a quoted
+// string, as used in config files.
+func (a *Alphabet) quote(i int) string {
+	return strconv.Quote(string(a.letters[i]))
+}
+
+// letter returns the character at position i in the alphabet.
+func (a *Alphabet) letter(i int) rune {
+	return a.letters[i]
+}
+
+// index returns the position of char in the alphabet, and false if char is
+// not a part of the alphabet.
+func (a *Alphabet) index(char rune) (int, bool) {
+	i, ok := a.indices[char]
+	return i, ok
+}
+
+// lookup returns the position of char in the alphabet. Uppercase characters
+// not in the alphabet are looked up as lowercase, in which case lowered is
+// true. ok is false if neither char nor its lowercase are in the alphabet.
+func (a *Alphabet) lookup(char rune) (index int, lowered bool, ok bool) {
+	if i, ok := a.indices[char]; ok {
+		return i, false, true
+	}
+
+	lower := unicode.ToLower(char)
+	if lower != char && unicode.ToUpper(lower) == char {
+		if i, ok := a.indices[lower]; ok {
+			return i, true, true
+		}
+	}
+	return -1, false, false
+}
+
+// Equal returns true if both alphabets contain the same characters in the
+// same order.
+func (a *Alphabet) Equal(b *Alphabet) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a == b || a.String() == b.String()
+}
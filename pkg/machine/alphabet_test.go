@@ -0,0 +1,145 @@
+package machine
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestNewAlphabet tests alphabet validation.
+func TestNewAlphabet(t *testing.T) {
+	for i, test := range []struct {
+		letters   string
+		shouldErr bool
+	}{
+		{
+			letters:   "abcdefghijklmnopqrstuvwxyz",
+			shouldErr: false,
+		},
+		{
+			letters:   "abäß",
+			shouldErr: false,
+		},
+		{
+			letters:   "",
+			shouldErr: true,
+		},
+		{
+			letters:   "abc",
+			shouldErr: true,
+		},
+		{
+			letters:   "abca",
+			shouldErr: true,
+		},
+		{
+			letters:   "ab\xff\xfe",
+			shouldErr: true,
+		},
+	} {
+		_, err := NewAlphabet(test.letters)
+		if test.shouldErr && err == nil {
+			t.Errorf("test %d: want error, got nil", i)
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("test %d: want nil, got %v", i, err)
+		}
+	}
+}
+
+// TestAlphabetEncryptDecrypt tests encryption and decryption using machines
+// of different alphabets.
+func TestAlphabetEncryptDecrypt(t *testing.T) {
+	opts := EncryptOptions{PreserveCase: true}
+	for i, test := range []struct {
+		alphabet *Alphabet
+		message  string
+	}{
+		{
+			alphabet: AlphanumericAlphabet,
+			message:  "Meet at 10:45, gate 7.",
+		},
+		{
+			alphabet: GermanAlphabet,
+			message:  "Grüße aus Köln!",
+		},
+		{
+			alphabet: PrintableAlphabet,
+			message:  "Hello, World! {x: 1}",
+		},
+	} {
+		encryptor := GenerateWithAlphabet(5, test.alphabet, rand.NewSource(int64(i)))
+		encryptor.SetOptions(opts)
+		decryptor := encryptor.Clone()
+
+		encrypted, err := encryptor.Encrypt(test.message)
+		if err != nil {
+			t.Fatalf("test %d: failed to encrypt: %v", i, err)
+		}
+
+		message, result := []rune(test.message), []rune(encrypted)
+		if len(message) != len(result) {
+			t.Fatalf("test %d: want %d characters, got %d", i, len(message), len(result))
+		}
+		for j, char := range message {
+			if _, _, ok := test.alphabet.lookup(char); !ok && result[j] != char {
+				t.Errorf("test %d: character %q not in alphabet was encrypted as %q", i, char, result[j])
+			}
+		}
+
+		decrypted, err := decryptor.Encrypt(encrypted)
+		if err != nil {
+			t.Fatalf("test %d: failed to decrypt: %v", i, err)
+		}
+		if decrypted != test.message {
+			t.Errorf("test %d: failed to decrypt: want %s, got %s", i, test.message, decrypted)
+		}
+	}
+}
+
+// TestAlphabetReadAndWrite tests writing and reading machines of different
+// alphabets.
+func TestAlphabetReadAndWrite(t *testing.T) {
+	unexported := cmp.AllowUnexported(
+		Machine{},
+		Rotors{},
+		Rotor{},
+		Plugboard{},
+		Reflector{},
+	)
+
+	for i, alphabet := range []*Alphabet{
+		LatinAlphabet,
+		AlphanumericAlphabet,
+		GermanAlphabet,
+		PrintableAlphabet,
+	} {
+		m := GenerateWithAlphabet(5, alphabet, rand.NewSource(int64(i)))
+		if err := Write(m, "../../test-data/generate/alphabet.json"); err != nil {
+			t.Fatalf("test %d: failed to write: %v", i, err)
+		}
+
+		r, err := Read("../../test-data/generate/alphabet.json")
+		if err != nil {
+			t.Fatalf("test %d: failed to read: %v", i, err)
+		}
+
+		if diff := cmp.Diff(*m, *r, unexported); diff != "" {
+			t.Errorf("test %d: mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestAlphabetMismatch tests that components that don't match a machine's
+// alphabet are rejected.
+func TestAlphabetMismatch(t *testing.T) {
+	m := GenerateWithSource(3, rand.NewSource(0))
+	if _, err := NewWithAlphabet(AlphanumericAlphabet, m.rotors, m.plugboard, m.reflector); err == nil {
+		t.Errorf("want error, got nil")
+	}
+
+	a := GenerateWithAlphabet(3, AlphanumericAlphabet, rand.NewSource(0))
+	if _, err := New(m.rotors, a.plugboard, m.reflector); err == nil {
+		t.Errorf("want error, got nil")
+	}
+}
@@ -0,0 +1,235 @@
+package machine
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+)
+
+// byteAlphabet is an alphabet of 256 characters, where the character at
+// position i represents the byte i. It's used by ByteMachine.
+var byteAlphabet = mustAlphabet(byteRunes())
+
+// ByteMachine is a variant of Machine that encrypts arbitrary binary data.
+// A ByteMachine's components operate on 256 values, one for each possible
+// byte, so rotors have 256 pathways, and plugboard and reflector have 256
+// connections. Unlike Machine, every byte is encrypted and moves the rotors,
+// so no structure of the plaintext is left unencrypted.
+//
+// Similar to Machine, encrypting data encrypted using a ByteMachine with the
+// same configuration decrypts it.
+type ByteMachine struct {
+	machine *Machine
+}
+
+// NewByteMachine creates and returns a new, initialized ByteMachine, and an
+// error if any of the given fields is invalid or doesn't have a size of 256.
+func NewByteMachine(rotors *Rotors, plugboard *Plugboard, reflector *Reflector) (*ByteMachine, error) {
+	m, err := NewWithAlphabet(byteAlphabet, rotors, plugboard, reflector)
+	if err != nil {
+		return nil, err
+	}
+	return &ByteMachine{machine: m}, nil
+}
+
+// GenerateByteMachine generates a ByteMachine with the specified number of
+// rotors using src as the source of randomness.
+func GenerateByteMachine(numberOfRotors int, src rand.Source) *ByteMachine {
+	return &ByteMachine{
+		machine: GenerateWithAlphabet(numberOfRotors, byteAlphabet, src),
+	}
+}
+
+// ReadByteMachine loads a ByteMachine from a JSON, YAML, or TOML file,
+// verifies its validity, and returns an error in case of invalid fields.
+func ReadByteMachine(path string) (*ByteMachine, error) {
+	m, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return toByteMachine(m)
+}
+
+// ParseByteMachine parses a given byte array into a ByteMachine, and
+// returns a pointer to it, and an error in case of invalid fields.
+func ParseByteMachine(contents []byte) (*ByteMachine, error) {
+	m, err := Parse(contents)
+	if err != nil {
+		return nil, err
+	}
+	return toByteMachine(m)
+}
+
+// WriteByteMachine writes a ByteMachine to a file in the same format as
+// Write, and returns an error if ByteMachine has invalid fields or writing
+// failed.
+func WriteByteMachine(b *ByteMachine, path string) error {
+	return Write(b.machine, path)
+}
+
+// toByteMachine returns a ByteMachine that wraps m, and an error if m
+// doesn't encrypt bytes.
+func toByteMachine(m *Machine) (*ByteMachine, error) {
+	if !m.alphabet.Equal(byteAlphabet) {
+		return nil, fmt.Errorf("machine is not a byte machine")
+	}
+	return &ByteMachine{machine: m}, nil
+}
+
+// byteRunes returns a string containing the runes 0 through 255 in order.
+func byteRunes() string {
+	runes := make([]rune, 256)
+	for i := range runes {
+		runes[i] = rune(i)
+	}
+	return string(runes)
+}
+
+// Encrypt encrypts data, and returns the encrypted bytes and an error if the
+// machine's fields are invalid.
+func (b *ByteMachine) Encrypt(data []byte) ([]byte, error) {
+	if err := b.Verify(); err != nil {
+		return nil, err
+	}
+
+	encrypted := make([]byte, len(data))
+	b.encrypt(encrypted, data, reverseConnections(b.machine))
+	return encrypted, nil
+}
+
+// encrypt encrypts src into dst, which must be at least as long as src.
+func (b *ByteMachine) encrypt(dst, src []byte, reversed [][]int) {
+	for i, char := range src {
+		dst[i] = byte(b.machine.encryptIndex(int(char), reversed))
+	}
+}
+
+// NewWriter returns an io.Writer that encrypts written bytes using b and
+// writes them to w.
+func (b *ByteMachine) NewWriter(w io.Writer) io.Writer {
+	s := &byteWriter{
+		b:   b,
+		w:   w,
+		err: b.Verify(),
+	}
+	if s.err == nil {
+		s.reversed = reverseConnections(b.machine)
+	}
+	return s
+}
+
+// NewReader returns an io.Reader that reads from r and returns the
+// encryption of read bytes using b.
+func (b *ByteMachine) NewReader(r io.Reader) io.Reader {
+	s := &byteReader{
+		b:   b,
+		r:   r,
+		err: b.Verify(),
+	}
+	if s.err == nil {
+		s.reversed = reverseConnections(b.machine)
+	}
+	return s
+}
+
+// Verify verifies that all components of the machine are initialized
+// correctly, and returns an error if not.
+func (b *ByteMachine) Verify() error {
+	if b.machine == nil {
+		return fmt.Errorf("no machine given")
+	}
+	if !b.machine.alphabet.Equal(byteAlphabet) {
+		return fmt.Errorf("machine is not a byte machine")
+	}
+	return b.machine.Verify()
+}
+
+// Clone returns a deep copy of the machine.
+func (b *ByteMachine) Clone() *ByteMachine {
+	return &ByteMachine{machine: b.machine.Clone()}
+}
+
+// Reset returns machine's rotors to their positions at creation, or at the
+// time the machine was read.
+func (b *ByteMachine) Reset() {
+	b.machine.Reset()
+}
+
+// State returns a snapshot of the current state of machine's rotors.
+func (b *ByteMachine) State() State {
+	return b.machine.State()
+}
+
+// SetState restores machine's rotors to the given state, and returns an
+// error if the state doesn't fit the machine.
+func (b *ByteMachine) SetState(state State) error {
+	return b.machine.SetState(state)
+}
+
+// Rotors returns machine's rotors.
+func (b *ByteMachine) Rotors() *Rotors {
+	return b.machine.rotors
+}
+
+// Plugboard returns machine's plugboard.
+func (b *ByteMachine) Plugboard() *Plugboard {
+	return b.machine.plugboard
+}
+
+// Reflector returns machine's reflector.
+func (b *ByteMachine) Reflector() *Reflector {
+	return b.machine.reflector
+}
+
+// byteWriter is an io.Writer that encrypts written bytes using a
+// ByteMachine.
+type byteWriter struct {
+	b        *ByteMachine
+	w        io.Writer
+	reversed [][]int
+	err      error
+}
+
+// Write encrypts p and writes the result to the underlying writer. If only
+// n bytes are written, the machine is moved back to the state reached after
+// encrypting n bytes, and later writes fail.
+func (w *byteWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	state := w.b.State()
+	encrypted := make([]byte, len(p))
+	w.b.encrypt(encrypted, p, w.reversed)
+	n, err := w.w.Write(encrypted)
+	if n < len(p) && err == nil {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		w.err = err
+		w.b.SetState(state)
+		w.b.machine.Advance(uint64(n))
+	}
+	return n, err
+}
+
+// byteReader is an io.Reader that returns the encryption of bytes read from
+// an underlying reader using a ByteMachine.
+type byteReader struct {
+	b        *ByteMachine
+	r        io.Reader
+	reversed [][]int
+	err      error
+}
+
+// Read reads from the underlying reader and places up to len(p) encrypted
+// bytes into p.
+func (r *byteReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err := r.r.Read(p)
+	r.b.encrypt(p[:n], p[:n], r.reversed)
+	return n, err
+}
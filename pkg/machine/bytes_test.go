@@ -0,0 +1,175 @@
+package machine
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestByteMachineEncryptDecrypt tests encryption and decryption of binary
+// data using a ByteMachine.
+func TestByteMachineEncryptDecrypt(t *testing.T) {
+	data := make([]byte, 4096)
+	rand.New(rand.NewSource(0)).Read(data)
+
+	encryptor := GenerateByteMachine(5, rand.NewSource(1))
+	decryptor := encryptor.Clone()
+
+	encrypted, err := encryptor.Encrypt(data)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	// A reflector with no unconnected values guarantees that no byte is
+	// encrypted as itself.
+	for i := range data {
+		if data[i] == encrypted[i] {
+			t.Errorf("byte %d encrypted as itself", i)
+		}
+	}
+
+	decrypted, err := decryptor.Encrypt(encrypted)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if !bytes.Equal(data, decrypted) {
+		t.Errorf("failed to decrypt")
+	}
+}
+
+// TestByteMachineStream compares the output of ByteMachine's writer and
+// reader with Encrypt.
+func TestByteMachineStream(t *testing.T) {
+	data, err := ioutil.ReadFile("../../LICENSE")
+	if err != nil {
+		t.Fatalf("failed to read LICENSE: %v", err)
+	}
+
+	m := GenerateByteMachine(3, rand.NewSource(0))
+	want, err := m.Clone().Encrypt(data)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	buffer := new(bytes.Buffer)
+	w := m.Clone().NewWriter(buffer)
+	for i := 0; i < len(data); i += 100 {
+		end := i + 100
+		if end > len(data) {
+			end = len(data)
+		}
+		if _, err := w.Write(data[i:end]); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	if !bytes.Equal(want, buffer.Bytes()) {
+		t.Errorf("writer: incorrect encryption")
+	}
+
+	got, err := ioutil.ReadAll(m.Clone().NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("reader: incorrect encryption")
+	}
+}
+
+// TestByteMachineState tests that restoring a snapshot of a ByteMachine
+// produces the same encryption.
+func TestByteMachineState(t *testing.T) {
+	data := make([]byte, 1024)
+	rand.New(rand.NewSource(0)).Read(data)
+
+	m := GenerateByteMachine(3, rand.NewSource(0))
+	if _, err := m.Encrypt(data); err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	state := m.State()
+
+	want, err := m.Encrypt(data)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	m.Reset()
+	if err := m.SetState(state); err != nil {
+		t.Fatalf("failed to set state: %v", err)
+	}
+
+	got, err := m.Encrypt(data)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("incorrect encryption after restoring state")
+	}
+}
+
+// shortWriter is an io.Writer that writes at most n bytes, and then fails.
+type shortWriter struct {
+	n int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.n {
+		w.n -= len(p)
+		return len(p), nil
+	}
+	n := w.n
+	w.n = 0
+	return n, io.ErrClosedPipe
+}
+
+// TestByteMachineShortWrite tests that a short write moves the machine only
+// by the number of written bytes.
+func TestByteMachineShortWrite(t *testing.T) {
+	data := make([]byte, 100)
+	rand.New(rand.NewSource(0)).Read(data)
+
+	for i, written := range []int{0, 1, 37, 99} {
+		m := GenerateByteMachine(3, rand.NewSource(int64(i)))
+		want := m.Clone()
+		if _, err := want.Encrypt(data[:written]); err != nil {
+			t.Fatalf("test %d: failed to encrypt: %v", i, err)
+		}
+
+		w := m.NewWriter(&shortWriter{n: written})
+		n, err := w.Write(data)
+		if n != written || err == nil {
+			t.Errorf("test %d: want %d bytes and an error, got %d bytes and %v", i, written, n, err)
+		}
+		if diff := cmp.Diff(want.State(), m.State()); diff != "" {
+			t.Errorf("test %d: state mismatch (-want +got):\n%s", i, diff)
+		}
+		if _, err := w.Write(data); err == nil {
+			t.Errorf("test %d: write after error: want error, got nil", i)
+		}
+	}
+}
+
+// TestByteMachineReadAndWrite tests writing and reading a ByteMachine.
+func TestByteMachineReadAndWrite(t *testing.T) {
+	m := GenerateByteMachine(3, rand.NewSource(0))
+	if err := WriteByteMachine(m, "../../test-data/generate/bytes.json"); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	r, err := ReadByteMachine("../../test-data/generate/bytes.json")
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+
+	unexported := cmp.AllowUnexported(ByteMachine{}, Machine{}, Rotors{}, Rotor{}, Plugboard{}, Reflector{})
+	if diff := cmp.Diff(*m, *r, unexported); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := ReadByteMachine("../../test-data/config-1.json"); err == nil {
+		t.Errorf("reading a text machine: want error, got nil")
+	}
+}
@@ -0,0 +1,144 @@
+package machine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Wiring is a named historical rotor or reflector wiring of LatinAlphabet.
+type Wiring struct {
+	Name    string // Name used to reference the wiring in config files.
+	Letters string // Letters that "a" to "z" are connected to, in order.
+	Notches string // Turnover notches of a rotor, empty for reflectors.
+}
+
+// Catalogue of historical Enigma wirings. Rotors VI, VII, and VIII have two
+// notches, and the Greek wheels Beta and Gamma don't step, so they have
+// none. Thin reflectors are used with a Greek wheel in the M4.
+var (
+	historicalRotors = []Wiring{
+		{Name: "I", Letters: "ekmflgdqvzntowyhxuspaibrcj", Notches: "q"},
+		{Name: "II", Letters: "ajdksiruxblhwtmcqgznpyfvoe", Notches: "e"},
+		{Name: "III", Letters: "bdfhjlcprtxvznyeiwgakmusqo", Notches: "v"},
+		{Name: "IV", Letters: "esovpzjayquirhxlnftgkdcmwb", Notches: "j"},
+		{Name: "V", Letters: "vzbrgityupsdnhlxawmjqofeck", Notches: "z"},
+		{Name: "VI", Letters: "jpgvoumfyqbenhzrdkasxlictw", Notches: "zm"},
+		{Name: "VII", Letters: "nzjhgrcxmyswboufaivlpekqdt", Notches: "zm"},
+		{Name: "VIII", Letters: "fkqhtlxocbjspdzramewniuygv", Notches: "zm"},
+		{Name: "Beta", Letters: "leyjvcnixwpbqmdrtakzgfuhos"},
+		{Name: "Gamma", Letters: "fsokanuerhmbtiycwlqpzxvgjd"},
+	}
+
+	historicalReflectors = []Wiring{
+		{Name: "UKW-A", Letters: "ejmzalyxvbwfcrquontspikhgd"},
+		{Name: "UKW-B", Letters: "yruhqsldpxngokmiebfzcwvjat"},
+		{Name: "UKW-C", Letters: "fvpjiaoyedrzxwgctkuqsbnmhl"},
+		{Name: "UKW-B-thin", Letters: "enkqauywjicopblmdxzvfthrgs"},
+		{Name: "UKW-C-thin", Letters: "rdobjntkvehmlfcwzaxgyipsuq"},
+	}
+)
+
+// HistoricalRotors returns the catalogue of historical rotor wirings.
+func HistoricalRotors() []Wiring {
+	return append([]Wiring(nil), historicalRotors...)
+}
+
+// HistoricalReflectors returns the catalogue of historical reflector
+// wirings.
+func HistoricalReflectors() []Wiring {
+	return append([]Wiring(nil), historicalReflectors...)
+}
+
+// NewHistoricalRotor returns a rotor with the historical wiring of the given
+// name, for example "III", for a machine in EnigmaMode, and an error if no
+// such wiring exists. Names are not case sensitive. The Greek wheels Beta
+// and Gamma are returned as stationary rotors.
+func NewHistoricalRotor(name string, position, ring int) (*Rotor, error) {
+	wiring, ok := findWiring(historicalRotors, name)
+	if !ok {
+		return nil, &ValidationError{Reason: "unknown rotor wiring", Got: strconv.Quote(name)}
+	}
+	if wiring.greek() {
+		return NewStationaryRotor(wiring.pathways(), position, ring)
+	}
+	return NewEnigmaRotor(wiring.pathways(), position, ring, wiring.notches())
+}
+
+// NewHistoricalReflector returns a reflector with the historical wiring of
+// the given name, for example "UKW-B", and an error if no such wiring exists.
+// Names are not case sensitive, and the "UKW-" prefix may be omitted.
+func NewHistoricalReflector(name string) (*Reflector, error) {
+	wiring, ok := findWiring(historicalReflectors, name)
+	if !ok {
+		return nil, &ValidationError{Reason: "unknown reflector wiring", Got: strconv.Quote(name)}
+	}
+
+	connections := make(map[int]int)
+	for i, pathway := range wiring.pathways() {
+		connections[i] = pathway
+	}
+	return NewReflector(connections)
+}
+
+// findWiring returns the wiring of the given name in catalogue, and false
+// if it doesn't exist.
+func findWiring(catalogue []Wiring, name string) (Wiring, bool) {
+	for _, wiring := range catalogue {
+		if strings.EqualFold(wiring.Name, name) || strings.EqualFold(wiring.Name, "UKW-"+name) {
+			return wiring, true
+		}
+	}
+	return Wiring{}, false
+}
+
+// wiringName returns the name of the wiring in catalogue that matches the
+// given pathways, and false if none does.
+func wiringName(catalogue []Wiring, pathways []int) (string, bool) {
+	for _, wiring := range catalogue {
+		if equalInts(wiring.pathways(), pathways) {
+			return wiring.Name, true
+		}
+	}
+	return "", false
+}
+
+// pathways returns the wiring's connections as positions in LatinAlphabet.
+func (w Wiring) pathways() []int {
+	return lettersToPositions(w.Letters)
+}
+
+// greek returns true if the wiring is of a Greek wheel, which has no notches
+// and doesn't move.
+func (w Wiring) greek() bool {
+	return w.Notches == ""
+}
+
+// notches returns the wiring's notches as positions in LatinAlphabet.
+func (w Wiring) notches() []int {
+	return lettersToPositions(w.Notches)
+}
+
+// lettersToPositions returns the positions of the given letters in
+// LatinAlphabet.
+func lettersToPositions(letters string) []int {
+	positions := make([]int, 0, len(letters))
+	for _, letter := range letters {
+		index, _ := LatinAlphabet.index(letter)
+		positions = append(positions, index)
+	}
+	return positions
+}
+
+// equalInts returns true if both slices contain the same elements in the
+// same order.
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
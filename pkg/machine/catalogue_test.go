@@ -0,0 +1,85 @@
+package machine
+
+import (
+	"testing"
+)
+
+// TestCatalogue tests that all historical wirings are valid.
+func TestCatalogue(t *testing.T) {
+	for _, wiring := range HistoricalRotors() {
+		rotor, err := NewHistoricalRotor(wiring.Name, 0, 0)
+		if err != nil {
+			t.Errorf("rotor %s: %v", wiring.Name, err)
+			continue
+		}
+		if name, ok := wiringName(historicalRotors, rotor.Pathways()); !ok || name != wiring.Name {
+			t.Errorf("rotor %s: incorrect wiring name %s", wiring.Name, name)
+		}
+	}
+
+	for _, wiring := range HistoricalReflectors() {
+		if _, err := NewHistoricalReflector(wiring.Name); err != nil {
+			t.Errorf("reflector %s: %v", wiring.Name, err)
+		}
+	}
+}
+
+// TestCatalogueNames tests looking up wirings by name.
+func TestCatalogueNames(t *testing.T) {
+	for i, test := range []struct {
+		name  string
+		valid bool
+	}{
+		{name: "UKW-B", valid: true},
+		{name: "ukw-b", valid: true},
+		{name: "B", valid: true},
+		{name: "c-thin", valid: true},
+		{name: "UKW-D", valid: false},
+		{name: "", valid: false},
+	} {
+		_, err := NewHistoricalReflector(test.name)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("test %d: %q valid: %t, want: %t", i, test.name, valid, test.valid)
+		}
+	}
+}
+
+// TestParseWiring tests parsing configs that reference historical wirings.
+func TestParseWiring(t *testing.T) {
+	identity := `{"a":"a","b":"b","c":"c","d":"d","e":"e","f":"f","g":"g","h":"h","i":"i","j":"j","k":"k","l":"l","m":"m",` +
+		`"n":"n","o":"o","p":"p","q":"q","r":"r","s":"s","t":"t","u":"u","v":"v","w":"w","x":"x","y":"y","z":"z"}`
+
+	for i, test := range []struct {
+		config string
+		valid  bool
+	}{
+		{
+			config: `{"rotors": [{"wiring": "VI", "position": "a", "step": 1, "cycle": 26}], "reflector": "UKW-C", "plugboard": {"connections": ` + identity + `}}`,
+			valid:  true,
+		},
+		{
+			config: `{"mode": "enigma", "rotors": [{"wiring": "I", "position": "a"}, {"wiring": "II", "position": "a"}, {"wiring": "III", "position": "a", "notches": ["a", "n"]}], "reflector": "B", "plugboard": {"connections": ` + identity + `}}`,
+			valid:  true,
+		},
+		{
+			config: `{"rotors": [{"wiring": "IX", "position": "a", "step": 1, "cycle": 26}], "reflector": "UKW-C", "plugboard": {"connections": ` + identity + `}}`,
+			valid:  false,
+		},
+		{
+			config: `{"rotors": [{"wiring": "I", "pathways": ["a"], "position": "a", "step": 1, "cycle": 26}], "reflector": "UKW-C", "plugboard": {"connections": ` + identity + `}}`,
+			valid:  false,
+		},
+		{
+			config: `{"rotors": [{"wiring": "I", "position": "a", "step": 1, "cycle": 26}], "reflector": "UKW-Z", "plugboard": {"connections": ` + identity + `}}`,
+			valid:  false,
+		},
+	} {
+		m, err := Parse([]byte(test.config))
+		if err == nil {
+			err = m.Verify()
+		}
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("test %d: valid: %t, want: %t, error: %v", i, valid, test.valid, err)
+		}
+	}
+}
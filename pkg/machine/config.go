@@ -1,47 +1,99 @@
 package machine
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
-// jsonMachine is used for (un)marshalling a Machine from/into a json
-// file. Fields in jsonMachine mirror those in a Machine but use string
-// arrays instead of int arrays.
+// jsonMachine is used for (un)marshalling a Machine from/into a JSON, YAML,
+// or TOML file. Fields in jsonMachine mirror those in a Machine but use
+// string arrays instead of int arrays. Mode, stepping, and alphabet are
+// omitted for machines in XenigmaMode that use the default stepper and
+// LatinAlphabet. Version is the version of the config schema, which older
+// configs are migrated from when parsed.
 type jsonMachine struct {
-	Rotors    []*jsonRotor   `json:"rotors"`
-	Reflector *jsonReflector `json:"reflector"`
-	Plugboard *jsonPlugboard `json:"plugboard"`
+	Version   int            `json:"version" yaml:"version" toml:"version"`
+	Mode      string         `json:"mode,omitempty" yaml:"mode,omitempty" toml:"mode,omitempty"`
+	Stepping  string         `json:"stepping,omitempty" yaml:"stepping,omitempty" toml:"stepping,omitempty"`
+	Alphabet  string         `json:"alphabet,omitempty" yaml:"alphabet,omitempty" toml:"alphabet,omitempty"`
+	Rotors    []*jsonRotor   `json:"rotors" yaml:"rotors" toml:"rotors"`
+	Reflector *jsonReflector `json:"reflector" yaml:"reflector" toml:"reflector"`
+	Plugboard *jsonPlugboard `json:"plugboard" yaml:"plugboard" toml:"plugboard"`
 }
 
 // jsonRotor mirrors Rotor struct and is used for json (un)marshalling.
+// Wiring is the name of a historical wiring used instead of pathways.
 type jsonRotor struct {
-	Pathways [alphabetSize]string `json:"pathways"`
-	Position string               `json:"position"`
-	Step     int                  `json:"step"`
-	Cycle    int                  `json:"cycle"`
+	Wiring     string   `json:"wiring,omitempty" yaml:"wiring,omitempty" toml:"wiring,omitempty"`
+	Pathways   []string `json:"pathways,omitempty" yaml:"pathways,omitempty" toml:"pathways,omitempty"`
+	Position   string   `json:"position" yaml:"position" toml:"position"`
+	Step       int      `json:"step" yaml:"step" toml:"step"`
+	Cycle      int      `json:"cycle" yaml:"cycle" toml:"cycle"`
+	Ring       string   `json:"ring,omitempty" yaml:"ring,omitempty" toml:"ring,omitempty"`
+	Notches    []string `json:"notches,omitempty" yaml:"notches,omitempty" toml:"notches,omitempty"`
+	Stationary *bool    `json:"stationary,omitempty" yaml:"stationary,omitempty" toml:"stationary,omitempty"`
 }
 
 // jsonReflector mirrors Reflector struct and is used for json (un)marshalling.
+// A reflector with a historical wiring is (un)marshalled as the name of the
+// wiring, for example "UKW-B", instead of a connections map. In TOML the name
+// is written as a field of the reflector's table.
 type jsonReflector struct {
-	Connections map[string]string `json:"connections"`
+	Name        string            `json:"-" yaml:"-" toml:"name,omitempty"`
+	Connections map[string]string `json:"connections" yaml:"connections" toml:"connections,omitempty"`
+}
+
+// UnmarshalJSON unmarshals either a wiring name or a connections map into
+// the reflector.
+func (r *jsonReflector) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &r.Name); err == nil {
+		return nil
+	}
+
+	type connections jsonReflector
+	return json.Unmarshal(data, (*connections)(r))
+}
+
+// MarshalJSON marshals the reflector as a wiring name if it has one, and as
+// a connections map otherwise.
+func (r *jsonReflector) MarshalJSON() ([]byte, error) {
+	if r.Name != "" {
+		return json.Marshal(r.Name)
+	}
+
+	type connections jsonReflector
+	return json.Marshal((*connections)(r))
 }
 
 // jsonPlugboard mirrors Plugboard struct and is used for json (un)marshalling.
+// A plugboard is given either as a full connections map, or as a space
+// separated list of connected pairs, for example "ab cd ef", in which case
+// unlisted characters are unplugged.
 type jsonPlugboard struct {
-	Connections map[string]string `json:"connections"`
+	Pairs       *string           `json:"pairs,omitempty" yaml:"pairs,omitempty" toml:"pairs,omitempty"`
+	Connections map[string]string `json:"connections,omitempty" yaml:"connections,omitempty" toml:"connections,omitempty"`
 }
 
-// Read loads a machine from a JSON file, verifies its validity, and returns
-// an error in case of invalid fields.
+// Read loads a machine from a JSON, YAML, or TOML file, verifies its
+// validity, and returns an error in case of invalid fields. The format is
+// determined by the file's extension, or by its contents if the extension
+// isn't one of ".json", ".yaml", ".yml", or ".toml".
 func Read(path string) (*Machine, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open %s", path)
+		if pathErr, ok := err.(*os.PathError); ok {
+			err = pathErr.Err
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
 	}
 	defer file.Close()
 
@@ -50,29 +102,38 @@ func Read(path string) (*Machine, error) {
 		return nil, fmt.Errorf("failed to read contents of %s", path)
 	}
 
-	m, err := Parse(fileContents)
+	format, ok := formatOf(path)
+	if !ok {
+		format = DetectFormat(fileContents)
+	}
+
+	m, err := ParseFormat(fileContents, format)
 	if err != nil {
 		return nil, fmt.Errorf("could not unmarshal %s: %w", path, err)
 	}
 	return m, m.Verify()
 }
 
-// Write writes a Machine to a file as JSON, and returns an error if Machine
-// has invalid fields or writing failed.
+// Write writes a Machine to a file as YAML or TOML if path has a ".yaml",
+// ".yml", or ".toml" extension, and as JSON otherwise. A file without one of
+// these extensions that already exists is written in its current format. An
+// error is returned if Machine has invalid fields, uses a stepper that isn't
+// built-in, or writing failed.
 func Write(m *Machine, path string) error {
-	if err := m.Verify(); err != nil {
-		return err
-	}
-
-	mToJSON := &jsonMachine{
-		Rotors:    marshalRotors(m.rotors),
-		Plugboard: marshalPlugboard(m.plugboard),
-		Reflector: marshalReflector(m.reflector),
+	format, ok := formatOf(path)
+	if current, err := ioutil.ReadFile(path); !ok && err == nil && len(bytes.TrimSpace(current)) != 0 {
+		format = DetectFormat(current)
 	}
+	return writeFormat(m, path, format)
+}
 
-	contents, err := json.MarshalIndent(mToJSON, "", "\t")
+// writeFormat writes a Machine to a file in the given format, and returns
+// an error if Machine has invalid fields, uses a stepper that isn't
+// built-in, or writing failed.
+func writeFormat(m *Machine, path string, format Format) error {
+	contents, err := marshalFormat(m, format)
 	if err != nil {
-		return fmt.Errorf("failed to write JSON file: %w", err)
+		return err
 	}
 
 	dir, _ := filepath.Split(path)
@@ -83,156 +144,461 @@ func Write(m *Machine, path string) error {
 
 	err = ioutil.WriteFile(path, contents, 0664)
 	if err != nil {
-		return fmt.Errorf("failed to write JSON file: %w", err)
+		return fmt.Errorf("failed to write %v file: %w", format, err)
 	}
 	return nil
 }
 
-// Parse parses a given byte array into a Machine, and returns a pointer
-// to it, and an error in case of invalid fields.
-func Parse(contents []byte) (_ *Machine, err error) {
-	var jsonM jsonMachine
-	if err := json.Unmarshal(contents, &jsonM); err != nil {
+// marshalMachine verifies a Machine, and creates and returns a jsonMachine
+// with the same fields. An error is returned if Machine has invalid fields,
+// or uses a stepper that isn't built-in.
+func marshalMachine(m *Machine) (*jsonMachine, error) {
+	if err := m.Verify(); err != nil {
 		return nil, err
 	}
 
+	stepping, ok := stepperName(m.Stepper())
+	if !ok {
+		return nil, fmt.Errorf("can't write machine with custom stepper %T", m.Stepper())
+	}
+
+	mToJSON := &jsonMachine{
+		Version:   ConfigVersion,
+		Rotors:    marshalRotors(m.rotors, m.mode, m.alphabet),
+		Plugboard: marshalPlugboard(m.plugboard, m.alphabet),
+		Reflector: marshalReflector(m.reflector, m.alphabet),
+	}
+	if m.mode != XenigmaMode {
+		mToJSON.Mode = m.mode.String()
+	}
+	if name, _ := stepperName(defaultStepper(m.mode)); stepping != name {
+		mToJSON.Stepping = stepping
+	}
+	if !m.alphabet.Equal(LatinAlphabet) {
+		mToJSON.Alphabet = m.alphabet.String()
+	}
+	return mToJSON, nil
+}
+
+// Parse parses a given byte array of JSON, YAML, or TOML into a Machine,
+// and returns a pointer to it, and an error in case of invalid fields. The
+// format is detected using DetectFormat.
+func Parse(contents []byte) (*Machine, error) {
+	return ParseFormat(contents, DetectFormat(contents))
+}
+
+// parseMachine parses a given jsonMachine into a Machine, and returns an
+// error in case of invalid fields. All invalid fields are reported at once
+// as ValidationErrors.
+func parseMachine(jsonM *jsonMachine) (*Machine, error) {
+	var errs ValidationErrors
+	if err := migrate(jsonM); err != nil {
+		errs.add("version", err)
+		return nil, errs
+	}
+
 	m := new(Machine)
-	m.rotors, err = parseRotors(jsonM.Rotors)
-	if err != nil {
-		return nil, err
+	var err error
+	m.mode, err = ParseMode(jsonM.Mode)
+	errs.add("mode", err)
+
+	if jsonM.Stepping != "" {
+		m.stepper, err = ParseStepper(jsonM.Stepping)
+		errs.add("stepping", err)
 	}
 
-	m.plugboard, err = parsePlugboard(jsonM.Plugboard)
+	m.alphabet, err = parseAlphabet(jsonM.Alphabet)
 	if err != nil {
-		return nil, err
+		errs.add("alphabet", err)
+		return nil, errs
 	}
 
-	m.reflector, err = parseReflector(jsonM.Reflector)
-	if err != nil {
+	m.rotors, err = parseRotors(jsonM.Rotors, m.mode, m.alphabet)
+	errs.add("", err)
+
+	m.plugboard, err = parsePlugboard(jsonM.Plugboard, m.alphabet)
+	errs.add("plugboard", err)
+
+	m.reflector, err = parseReflector(jsonM.Reflector, m.alphabet)
+	errs.add("reflector", err)
+
+	if len(errs) == 0 {
+		errs.add("", m.Verify())
+	}
+	if err := errs.err(); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
+// parseAlphabet parses a given string into an Alphabet, and returns an error
+// if the alphabet is invalid. LatinAlphabet is returned if the string is
+// empty.
+func parseAlphabet(parse string) (*Alphabet, error) {
+	if parse == "" || parse == LatinAlphabet.String() {
+		return LatinAlphabet, nil
+	}
+	return NewAlphabet(parse)
+}
+
 // parseRotors parses a given slice of jsonRotor into a Rotors, and returns an
 // error if any of the rotors has invalid fields.
-func parseRotors(parse []*jsonRotor) (_ *Rotors, err error) {
+func parseRotors(parse []*jsonRotor, mode Mode, alphabet *Alphabet) (*Rotors, error) {
 	if len(parse) == 0 {
-		return nil, fmt.Errorf("no rotors given")
+		return nil, &ValidationError{Path: "rotors", Reason: "no rotors given"}
 	}
 
+	var errs ValidationErrors
 	rotors := make([]*Rotor, len(parse))
 	for i, toParse := range parse {
-		rotors[i], err = parseRotor(toParse)
-		if err != nil {
-			return nil, err
-		}
+		var err error
+		rotors[i], err = parseRotor(toParse, mode, alphabet)
+		errs.add(fmt.Sprintf("rotors[%d]", i), err)
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
 	}
 	return NewRotors(rotors)
 }
 
 // parseRotor parses a given jsonRotor into a Rotor, and returns an error
-// if Rotor has invalid fields.
-func parseRotor(parse *jsonRotor) (*Rotor, error) {
-	var pathways [alphabetSize]int
-	for i, connection := range parse.Pathways {
-		num, ok := strToInt(connection)
-		if !ok {
-			return nil, fmt.Errorf("invalid rotor pathway %v", connection)
+// if Rotor has invalid fields. In EnigmaMode step and cycle may be omitted,
+// in which case they are set to their defaults.
+func parseRotor(parse *jsonRotor, mode Mode, alphabet *Alphabet) (*Rotor, error) {
+	if parse == nil {
+		return nil, &ValidationError{Reason: "no rotor given"}
+	}
+
+	var errs ValidationErrors
+	var pathways []int
+	var defaultNotches []int
+	var defaultStationary bool
+	if parse.Wiring != "" {
+		wiring, ok := findWiring(historicalRotors, parse.Wiring)
+		switch {
+		case len(parse.Pathways) != 0:
+			errs = append(errs, &ValidationError{Path: "wiring", Reason: "rotor can't have both a wiring and pathways"})
+		case !alphabet.Equal(LatinAlphabet):
+			errs = append(errs, &ValidationError{Path: "wiring", Reason: "rotor wirings require the english alphabet", Got: strconv.Quote(parse.Wiring)})
+		case !ok:
+			errs = append(errs, &ValidationError{Path: "wiring", Reason: "unknown rotor wiring", Got: strconv.Quote(parse.Wiring)})
+		default:
+			pathways = wiring.pathways()
+			if mode == EnigmaMode {
+				defaultNotches = wiring.notches()
+				defaultStationary = wiring.greek()
+			}
+		}
+	} else if len(parse.Pathways) != alphabet.Size() {
+		errs = append(errs, &ValidationError{
+			Path:   "pathways",
+			Reason: "invalid number of rotor pathways",
+			Got:    strconv.Itoa(len(parse.Pathways)),
+			Want:   strconv.Itoa(alphabet.Size()),
+		})
+	} else {
+		pathways = make([]int, len(parse.Pathways))
+		for i, connection := range parse.Pathways {
+			num, ok := strToInt(connection, alphabet)
+			if !ok {
+				errs = append(errs, notInAlphabet(fmt.Sprintf("pathways[%d]", i), connection))
+			}
+			pathways[i] = num
+		}
+		if len(errs) == 0 {
+			errs = append(errs, verifyPermutation("pathways", "pathway", pathways, strconv.Itoa, alphabet.quote)...)
 		}
-		pathways[i] = num
 	}
 
-	position, ok := strToInt(parse.Position)
+	position, ok := strToInt(parse.Position, alphabet)
 	if !ok {
-		return nil, fmt.Errorf("invalid rotor position %v", position)
+		errs = append(errs, notInAlphabet("position", parse.Position))
 	}
 
-	return NewRotor(pathways, position, parse.Step, parse.Cycle)
+	ring := 0
+	if parse.Ring != "" {
+		ring, ok = strToInt(parse.Ring, alphabet)
+		if !ok {
+			errs = append(errs, notInAlphabet("ring", parse.Ring))
+		}
+	}
+
+	notches := defaultNotches
+	if parse.Notches != nil {
+		notches = nil
+	}
+	for i, notch := range parse.Notches {
+		num, ok := strToInt(notch, alphabet)
+		if !ok {
+			errs = append(errs, notInAlphabet(fmt.Sprintf("notches[%d]", i), notch))
+			continue
+		}
+		notches = append(notches, num)
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	step, cycle := parse.Step, parse.Cycle
+	if mode == EnigmaMode && step == 0 && cycle == 0 {
+		step, cycle = DefaultStep, len(pathways)
+	}
+
+	errs = verifyRotorSettings(len(pathways), position, step, cycle, alphabet.quote)
+	errs = append(errs, verifyRingFields(len(pathways), ring, notches, alphabet.quote)...)
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	rotor := newRotor(pathways, position, step, cycle)
+	rotor.ring = ring
+	rotor.notches = notches
+	rotor.stationary = defaultStationary
+	if parse.Stationary != nil {
+		rotor.stationary = *parse.Stationary
+	}
+	return rotor, nil
 }
 
 // parsePlugboard parses a given jsonPlugboard into a Plugboard, and returns
 // an error if Plugboard has invalid fields.
-func parsePlugboard(parse *jsonPlugboard) (*Plugboard, error) {
+func parsePlugboard(parse *jsonPlugboard, alphabet *Alphabet) (*Plugboard, error) {
+	if parse != nil && parse.Pairs != nil {
+		if parse.Connections != nil {
+			return nil, &ValidationError{Reason: "plugboard can't have both pairs and connections"}
+		}
+
+		var errs ValidationErrors
+		plugboard, err := parsePairs(*parse.Pairs, alphabet)
+		errs.add("pairs", err)
+		if err := errs.err(); err != nil {
+			return nil, err
+		}
+		return plugboard, nil
+	}
 	if parse == nil || parse.Connections == nil {
-		return nil, fmt.Errorf("no plugboard given")
+		return nil, &ValidationError{Reason: "no plugboard connections or pairs given"}
 	}
-	if len(parse.Connections) != alphabetSize {
-		return nil, fmt.Errorf("invalid plugboard size %v, expected %v", len(parse.Connections), alphabetSize)
+
+	connections, err := parseConnections(parse.Connections, alphabet)
+	if err != nil {
+		return nil, err
 	}
+	return NewPlugboard(connections)
+}
 
+// parsePairs parses a space separated list of connected pairs into a
+// Plugboard, and returns an error if a pair is invalid, or if a character
+// appears in more than one pair. Characters that don't appear in any pair
+// are connected to themselves.
+func parsePairs(parse string, alphabet *Alphabet) (*Plugboard, error) {
+	return parsePairList(strings.Fields(parse), alphabet)
+}
+
+// parsePairList parses a list of connected pairs into a Plugboard similar
+// to parsePairs.
+func parsePairList(parse []string, alphabet *Alphabet) (*Plugboard, error) {
+	var errs ValidationErrors
 	connections := make(map[int]int)
-	for key, value := range parse.Connections {
-		k, ok := strToInt(key)
-		if !ok {
-			return nil, fmt.Errorf("invalid plugboard key %v", key)
+	pairs := make(map[int]string)
+	for _, pair := range parse {
+		chars := []rune(pair)
+		if len(chars) != 2 {
+			errs = append(errs, &ValidationError{Reason: "invalid plugboard pair, expected two characters", Got: strconv.Quote(pair)})
+			continue
 		}
-		v, ok := strToInt(value)
-		if !ok {
-			return nil, fmt.Errorf("invalid plugboard value %v", value)
+
+		var ends [2]int
+		valid := true
+		for i, char := range chars {
+			index, _, ok := alphabet.lookup(char)
+			if !ok {
+				errs = append(errs, &ValidationError{
+					Reason: fmt.Sprintf("invalid plugboard pair, %q is not in the alphabet", char),
+					Got:    strconv.Quote(pair),
+				})
+				valid = false
+				continue
+			}
+			if other, ok := pairs[index]; ok {
+				errs = append(errs, &ValidationError{
+					Reason: fmt.Sprintf("duplicate plugboard character %q, also in pair %q", char, other),
+					Got:    strconv.Quote(pair),
+				})
+				valid = false
+				continue
+			}
+			pairs[index] = pair
+			ends[i] = index
+		}
+
+		if valid {
+			connections[ends[0]] = ends[1]
+			connections[ends[1]] = ends[0]
+		}
+	}
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < alphabet.Size(); i++ {
+		if _, ok := connections[i]; !ok {
+			connections[i] = i
 		}
-		connections[k] = v
 	}
 	return NewPlugboard(connections)
 }
 
 // parseReflector parses a given jsonReflector into a Reflector, and returns
 // an error if Reflector has invalid fields.
-func parseReflector(parse *jsonReflector) (*Reflector, error) {
+func parseReflector(parse *jsonReflector, alphabet *Alphabet) (*Reflector, error) {
+	if parse != nil && parse.Name != "" {
+		if !alphabet.Equal(LatinAlphabet) {
+			return nil, &ValidationError{Reason: "reflector wirings require the english alphabet", Got: strconv.Quote(parse.Name)}
+		}
+		return NewHistoricalReflector(parse.Name)
+	}
 	if parse == nil || parse.Connections == nil {
-		return nil, fmt.Errorf("no reflector given")
+		return nil, &ValidationError{Reason: "no reflector connections or name given"}
 	}
-	if len(parse.Connections) != alphabetSize {
-		return nil, fmt.Errorf("invalid reflector size %v, expected %v", len(parse.Connections), alphabetSize)
+
+	connections, err := parseConnections(parse.Connections, alphabet)
+	if err != nil {
+		return nil, err
+	}
+	return NewReflector(connections)
+}
+
+// parseConnections parses the connections of a plugboard or a reflector,
+// and returns all invalid connections. Connections must connect every
+// character of the alphabet symmetrically.
+func parseConnections(parse map[string]string, alphabet *Alphabet) (map[int]int, error) {
+	keys := make([]string, 0, len(parse))
+	for key := range parse {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
+	var errs ValidationErrors
 	connections := make(map[int]int)
-	for key, value := range parse.Connections {
-		k, ok := strToInt(key)
+	for _, key := range keys {
+		path := fmt.Sprintf("connections[%s]", strconv.Quote(key))
+		k, ok := strToInt(key, alphabet)
 		if !ok {
-			return nil, fmt.Errorf("invalid reflector key %v", key)
+			errs = append(errs, notInAlphabet(path, key))
 		}
-		v, ok := strToInt(value)
+		v, ok := strToInt(parse[key], alphabet)
 		if !ok {
-			return nil, fmt.Errorf("invalid reflector value %v", value)
+			errs = append(errs, notInAlphabet(path, parse[key]))
 		}
 		connections[k] = v
 	}
-	return NewReflector(connections)
+	if len(errs) != 0 {
+		return nil, errs
+	}
+
+	if len(connections) != alphabet.Size() {
+		var missing []string
+		for i := 0; i < alphabet.Size(); i++ {
+			if _, ok := connections[i]; !ok {
+				missing = append(missing, alphabet.quote(i))
+			}
+		}
+		return nil, &ValidationError{
+			Path:   "connections",
+			Reason: "missing connections of " + strings.Join(missing, ", "),
+			Got:    fmt.Sprintf("%d connections", len(connections)),
+			Want:   fmt.Sprintf("%d connections", alphabet.Size()),
+		}
+	}
+
+	if err := verifyConnectionMap(connections, alphabet.quote).err(); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
+// notInAlphabet returns an error of a field whose value isn't a character
+// of the alphabet.
+func notInAlphabet(path, value string) *ValidationError {
+	return &ValidationError{Path: path, Reason: "character is not in the alphabet", Got: strconv.Quote(value)}
 }
 
 // marshalRotors creates and returns a slice of jsonRotor with the same
 // fields as given Rotors.
-func marshalRotors(rotors *Rotors) []*jsonRotor {
+func marshalRotors(rotors *Rotors, mode Mode, alphabet *Alphabet) []*jsonRotor {
 	marshalled := make([]*jsonRotor, rotors.count)
 	for i, r := range rotors.rotors {
-		marshalled[i] = marshalRotor(r)
+		marshalled[i] = marshalRotor(r, mode, alphabet)
 	}
 	return marshalled
 }
 
 // marshalRotor creates and returns a jsonRotor with the same fields
-// as given Rotor.
-func marshalRotor(rotor *Rotor) *jsonRotor {
-	var pathways [alphabetSize]string
-	for i, pathway := range rotor.pathways {
-		pathways[i] = intToStr(pathway)
-	}
-
-	return &jsonRotor{
-		Pathways: pathways,
-		Position: intToStr(rotor.position),
+// as given Rotor. Ring setting is only included in EnigmaMode. Rotors with
+// a historical wiring are marshalled using the wiring's name, in which case
+// notches are omitted if they match the wiring's.
+func marshalRotor(rotor *Rotor, mode Mode, alphabet *Alphabet) *jsonRotor {
+	marshalled := &jsonRotor{
+		Position: intToStr(rotor.position, alphabet),
 		Step:     rotor.step,
 		Cycle:    rotor.cycle,
 	}
+	if mode == EnigmaMode {
+		marshalled.Ring = intToStr(rotor.ring, alphabet)
+	}
+
+	defaultStationary := false
+	if name, ok := historicalName(historicalRotors, rotor.pathways, alphabet); ok {
+		marshalled.Wiring = name
+		wiring, _ := findWiring(historicalRotors, name)
+		defaultStationary = mode == EnigmaMode && wiring.greek()
+	} else {
+		marshalled.Pathways = make([]string, len(rotor.pathways))
+		for i, pathway := range rotor.pathways {
+			marshalled.Pathways[i] = intToStr(pathway, alphabet)
+		}
+	}
+
+	if rotor.stationary || defaultStationary {
+		stationary := rotor.stationary
+		marshalled.Stationary = &stationary
+	}
+
+	if marshalled.Wiring != "" && mode == EnigmaMode {
+		wiring, _ := findWiring(historicalRotors, marshalled.Wiring)
+		if equalInts(wiring.notches(), rotor.notches) {
+			return marshalled
+		}
+	}
+	for _, notch := range rotor.notches {
+		marshalled.Notches = append(marshalled.Notches, intToStr(notch, alphabet))
+	}
+	return marshalled
 }
 
 // marshalPlugboard creates and returns a jsonPlugboard with the same fields
-// as given Plugboard.
-func marshalPlugboard(plugboard *Plugboard) *jsonPlugboard {
+// as given Plugboard. Plugboards are marshalled as a list of pairs, unless
+// the alphabet contains characters that can't be written in a list of
+// pairs, such as spaces.
+func marshalPlugboard(plugboard *Plugboard, alphabet *Alphabet) *jsonPlugboard {
+	if pairable(alphabet) {
+		var pairs []string
+		for i := 0; i < plugboard.Size(); i++ {
+			if j := plugboard.connections[i]; i < j {
+				pairs = append(pairs, intToStr(i, alphabet)+intToStr(j, alphabet))
+			}
+		}
+
+		joined := strings.Join(pairs, " ")
+		return &jsonPlugboard{
+			Pairs: &joined,
+		}
+	}
+
 	connections := make(map[string]string)
 	for k, v := range plugboard.connections {
-		connections[intToStr(k)] = intToStr(v)
+		connections[intToStr(k, alphabet)] = intToStr(v, alphabet)
 	}
 
 	return &jsonPlugboard{
@@ -242,10 +608,20 @@ func marshalPlugboard(plugboard *Plugboard) *jsonPlugboard {
 
 // marshalReflector creates and returns a jsonReflector with the same fields
 // as given Reflector.
-func marshalReflector(reflector *Reflector) *jsonReflector {
+func marshalReflector(reflector *Reflector, alphabet *Alphabet) *jsonReflector {
+	pathways := make([]int, reflector.Size())
+	for k, v := range reflector.connections {
+		pathways[k] = v
+	}
+	if name, ok := historicalName(historicalReflectors, pathways, alphabet); ok {
+		return &jsonReflector{
+			Name: name,
+		}
+	}
+
 	connections := make(map[string]string)
 	for k, v := range reflector.connections {
-		connections[intToStr(k)] = intToStr(v)
+		connections[intToStr(k, alphabet)] = intToStr(v, alphabet)
 	}
 
 	return &jsonReflector{
@@ -253,17 +629,44 @@ func marshalReflector(reflector *Reflector) *jsonReflector {
 	}
 }
 
-// strToInt verifies that a given string contains one alphabetical
-// character and returns character's position in the alphabet.
-func strToInt(str string) (int, bool) {
-	if len(str) == 1 && unicode.IsLetter(rune(str[0])) {
-		return int(byte(unicode.ToLower(rune(str[0]))) - 'a'), true
+// historicalName returns the name of the historical wiring in catalogue that
+// matches the given pathways, and false if none does or alphabet isn't
+// LatinAlphabet.
+func historicalName(catalogue []Wiring, pathways []int, alphabet *Alphabet) (string, bool) {
+	if !alphabet.Equal(LatinAlphabet) {
+		return "", false
+	}
+	return wiringName(catalogue, pathways)
+}
+
+// pairable returns true if all characters of the alphabet are graphic
+// characters other than spaces, so they can be written as pairs.
+func pairable(alphabet *Alphabet) bool {
+	for _, letter := range alphabet.letters {
+		if !unicode.IsGraphic(letter) || unicode.IsSpace(letter) {
+			return false
+		}
+	}
+	return true
+}
+
+// strToInt verifies that a given string contains one character of the
+// given alphabet and returns character's position in the alphabet.
+func strToInt(str string, alphabet *Alphabet) (int, bool) {
+	char, size := utf8.DecodeRuneInString(str)
+	if size == 0 || size != len(str) {
+		return -1, false
+	}
+
+	index, _, ok := alphabet.lookup(char)
+	if !ok {
+		return -1, false
 	}
-	return -1, false
+	return index, true
 }
 
-// intToStr returns a one character string representing the ASCII position
-// of the given integer.
-func intToStr(num int) string {
-	return fmt.Sprintf("%c", byte(num)+'a')
+// intToStr returns a one character string representing the character at
+// the given position of the alphabet.
+func intToStr(num int, alphabet *Alphabet) string {
+	return string(alphabet.letter(num))
 }
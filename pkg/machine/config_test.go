@@ -18,7 +18,7 @@ func TestRead(t *testing.T) {
 	for i := 1; i <= correctCount; i++ {
 		_, err := Read(fmt.Sprintf("../../test-data/config-%d.json", i))
 		if err != nil {
-			t.Errorf("correct %d: produced error %w", i, err)
+			t.Errorf("correct %d: produced error %v", i, err)
 		}
 	}
 
@@ -43,7 +43,7 @@ func TestWrite(t *testing.T) {
 		count := rand.Intn(100) + 3
 		err := Write(Generate(count), "../../test-data/generate/generated.json")
 		if err != nil {
-			t.Errorf("test %d: failed to write: %w", i, err)
+			t.Errorf("test %d: failed to write: %v", i, err)
 		}
 
 		_, err = os.Open("../../test-data/generate/generated.json")
@@ -75,12 +75,12 @@ func TestReadAndWrite(t *testing.T) {
 		m := Generate(rand.Intn(100) + 3)
 		err := Write(m, "../../test-data/generate/generated.json")
 		if err != nil {
-			t.Errorf("failed to write: %w", err)
+			t.Errorf("failed to write: %v", err)
 		}
 
 		r, err := Read("../../test-data/generate/generated.json")
 		if err != nil {
-			t.Errorf("failed to read: %w", err)
+			t.Errorf("failed to read: %v", err)
 		}
 
 		if diff := cmp.Diff(*m, *r, unexported); diff != "" {
@@ -88,3 +88,55 @@ func TestReadAndWrite(t *testing.T) {
 		}
 	}
 }
+
+// TestParsePairs tests parsing plugboards given as a list of pairs.
+func TestParsePairs(t *testing.T) {
+	for i, test := range []struct {
+		pairs string
+		want  map[int]int
+		err   string
+	}{
+		{
+			pairs: "",
+			want:  map[int]int{},
+		},
+		{
+			pairs: "AB cd  ZY",
+			want:  map[int]int{0: 1, 1: 0, 2: 3, 3: 2, 25: 24, 24: 25},
+		},
+		{
+			pairs: "AB CA",
+			err:   `duplicate plugboard character 'A', also in pair "AB" (got "CA")`,
+		},
+		{
+			pairs: "AB C",
+			err:   `invalid plugboard pair, expected two characters (got "C")`,
+		},
+		{
+			pairs: "A1",
+			err:   `invalid plugboard pair, '1' is not in the alphabet (got "A1")`,
+		},
+	} {
+		plugboard, err := parsePairs(test.pairs, LatinAlphabet)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("test %d: incorrect error, want: %s, got: %v", i, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to parse: %v", i, err)
+			continue
+		}
+
+		for j := 0; j < LatinAlphabet.Size(); j++ {
+			want, ok := test.want[j]
+			if !ok {
+				want = j
+			}
+			if got := plugboard.PlugIn(j); got != want {
+				t.Errorf("test %d: incorrect connection of %d, want: %d, got: %d", i, j, want, got)
+			}
+		}
+	}
+}
@@ -1,56 +1,134 @@
 package machine
 
 import (
-	"bytes"
-	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// EncryptOptions are options that modify how a message is encrypted. The
+// options of a machine are set using SetOptions, and are used by Encrypt,
+// EncryptParallel, NewWriter, and NewReader.
+type EncryptOptions struct {
+	// PreserveCase restores each encrypted letter to the case of the
+	// original letter. Letters are still encrypted case-insensitively, so
+	// a message encrypted with PreserveCase is decrypted with its original
+	// case if PreserveCase is also used for decryption.
+	PreserveCase bool
+
+	// Transliterate replaces non-ASCII letters that are not a part of the
+	// machine's alphabet with their closest ASCII equivalents before
+	// encryption, for example "é" is encrypted as "e", and "ß" as "ss".
+	// Letters without an equivalent in the alphabet are returned without
+	// change.
+	Transliterate bool
+}
+
 // Encrypt encrypts a string message, and return the encrypted string and an
 // error if the machine's fields are invalid. When encrypting uppercase and
-// lowercase letters produce the same results. Non-alphabetical characters are
-// returned without change, and don't affect rotors' movement (rotors are not
-// shifted).
+// lowercase letters produce the same results, unless both are a part of the
+// machine's alphabet. Only characters of the machine's alphabet are
+// encrypted. Other characters are returned without change, and don't affect
+// rotors' movement (rotors are not shifted).
 func (m *Machine) Encrypt(message string) (string, error) {
 	if err := m.Verify(); err != nil {
 		return "", err
 	}
+	return string(m.encryptBytes([]byte(message), reverseConnections(m), m.opts)), nil
+}
+
+// encryptBytes encrypts UTF-8 encoded text using Machine m and the given
+// options, and returns the encrypted bytes. Invalid UTF-8 sequences are
+// returned without change.
+func (m *Machine) encryptBytes(text []byte, reversed [][]int, opts EncryptOptions) []byte {
+	encrypted := make([]byte, 0, len(text))
+	for len(text) > 0 {
+		char, size := utf8.DecodeRune(text)
+		if m.alphabet.count(char, opts) == 0 {
+			encrypted = append(encrypted, text[:size]...)
+		} else {
+			encrypted = m.appendRune(encrypted, char, reversed, opts)
+		}
+		text = text[size:]
+	}
+	return encrypted
+}
+
+// appendRune encrypts one rune using Machine m, and appends the result to
+// dst. Uppercase and lowercase letters produce the same results, unless
+// opts.PreserveCase is set. char must be encryptable using opts.
+func (m *Machine) appendRune(dst []byte, char rune, reversed [][]int, opts EncryptOptions) []byte {
+	var buffer [utf8.UTFMax]byte
+	if index, lowered, ok := m.alphabet.lookup(char); ok {
+		encrypted := m.alphabet.letter(m.encryptIndex(index, reversed))
+		if opts.PreserveCase && lowered {
+			encrypted = unicode.ToUpper(encrypted)
+		}
+		return append(dst, buffer[:utf8.EncodeRune(buffer[:], encrypted)]...)
+	}
+
+	for _, letter := range transliterations[unicode.ToLower(char)] {
+		index, _ := m.alphabet.index(letter)
+		encrypted := m.alphabet.letter(m.encryptIndex(index, reversed))
+		if opts.PreserveCase && unicode.IsUpper(char) {
+			encrypted = unicode.ToUpper(encrypted)
+		}
+		dst = append(dst, buffer[:utf8.EncodeRune(buffer[:], encrypted)]...)
+	}
+	return dst
+}
+
+// count returns the number of letters encrypted when char is encrypted using
+// the given options, which is the number of steps rotors move. Characters
+// are transliterated only if all characters of their transliteration are a
+// part of the alphabet.
+func (a *Alphabet) count(char rune, opts EncryptOptions) uint64 {
+	if _, _, ok := a.lookup(char); ok {
+		return 1
+	}
+	if !opts.Transliterate {
+		return 0
+	}
 
-	reversed := reverseConnections(m)
-	buffer := new(bytes.Buffer)
-	for _, char := range strings.ToLower(message) {
-		buffer.WriteByte(m.encryptChar(byte(char), reversed))
+	transliteration := transliterations[unicode.ToLower(char)]
+	for _, letter := range transliteration {
+		if _, ok := a.index(letter); !ok {
+			return 0
+		}
 	}
-	return buffer.String(), nil
+	return uint64(len(transliteration))
 }
 
-// encryptChar encrypts one byte using Machine m. Arguments are the byte to
-// encrypt and the reversed connections to use in the reverse cycle.
-func (m *Machine) encryptChar(char byte, reversed [][alphabetSize]int) byte {
-	if !unicode.IsLetter(rune(char)) {
-		return char
+// encryptIndex encrypts one character, represented by its position in the
+// machine's alphabet, using Machine m. Arguments are the position to encrypt
+// and the reversed connections to use in the reverse cycle.
+func (m *Machine) encryptIndex(char int, reversed [][]int) int {
+	if m.mode == EnigmaMode {
+		return m.encryptEnigma(char, reversed)
 	}
 
+	size := m.alphabet.Size()
+
 	encrypted := m.plugboard.PlugIn(char)
 	for i := 0; i < m.rotors.count; i++ {
-		index := (encrypted + m.rotors.rotors[i].position) % alphabetSize
+		index := (encrypted + m.rotors.rotors[i].position) % size
 		encrypted = m.rotors.rotors[i].pathways[index]
 	}
 
 	encrypted = m.reflector.Reflect(encrypted)
 	for i := m.rotors.count - 1; i >= 0; i-- {
-		encrypted = (reversed[i][encrypted] - m.rotors.rotors[i].position + alphabetSize) % alphabetSize
+		encrypted = (reversed[i][encrypted] - m.rotors.rotors[i].position + size) % size
 	}
-	m.rotors.takeStep()
+	m.Stepper().Step(m.rotors.rotors)
 
 	return m.plugboard.PlugOut(encrypted)
 }
 
 // reverseConnections returns a reversed list of the machine's pathway
 // connections.
-func reverseConnections(m *Machine) [][alphabetSize]int {
-	reversed := make([][alphabetSize]int, m.rotors.count)
+func reverseConnections(m *Machine) [][]int {
+	reversed := make([][]int, m.rotors.count)
 	for i, rotor := range m.rotors.rotors {
+		reversed[i] = make([]int, len(rotor.pathways))
 		for j, val := range rotor.pathways {
 			reversed[i][val] = j
 		}
@@ -49,12 +49,12 @@ func TestEncrypt(t *testing.T) {
 	} {
 		m, err := Read(test.path)
 		if err != nil {
-			t.Errorf("test %d: failed to read machine: %w", i, err)
+			t.Errorf("test %d: failed to read machine: %v", i, err)
 		}
 
 		got, err := m.Encrypt(test.message)
 		if err != nil {
-			t.Errorf("test %d: failed to encrypt: %w", i, err)
+			t.Errorf("test %d: failed to encrypt: %v", i, err)
 		}
 
 		if got != test.want {
@@ -109,12 +109,12 @@ func TestEncryptDecrypt(t *testing.T) {
 	} {
 		encrypted, err := encryptor.Encrypt(message)
 		if err != nil {
-			t.Errorf("failed to encrypt message: %w", err)
+			t.Errorf("failed to encrypt message: %v", err)
 		}
 
 		decrypted, err := decryptor.Encrypt(encrypted)
 		if err != nil {
-			t.Errorf("failed to encrypt message: %w", err)
+			t.Errorf("failed to encrypt message: %v", err)
 		}
 
 		if decrypted != strings.ToLower(message) {
@@ -131,12 +131,12 @@ func TestReadWriteEncrypt(t *testing.T) {
 	m := Generate(rand.Intn(100) + 3)
 	err := Write(m, "../../test-data/generate/generated.json")
 	if err != nil {
-		t.Errorf("failed to write machine: %w", err)
+		t.Errorf("failed to write machine: %v", err)
 	}
 
 	r, err := Read("../../test-data/generate/generated.json")
 	if err != nil {
-		t.Errorf("failed to read machine: %w", err)
+		t.Errorf("failed to read machine: %v", err)
 	}
 
 	for _, message := range []string{
@@ -147,11 +147,11 @@ func TestReadWriteEncrypt(t *testing.T) {
 	} {
 		original, err := m.Encrypt(message)
 		if err != nil {
-			t.Errorf("failed to encrypt: %w", err)
+			t.Errorf("failed to encrypt: %v", err)
 		}
 		read, err := r.Encrypt(message)
 		if err != nil {
-			t.Errorf("failed to encrypt: %w", err)
+			t.Errorf("failed to encrypt: %v", err)
 		}
 
 		if original != read {
@@ -173,9 +173,121 @@ func TestEncryptCharNonAlpha(t *testing.T) {
 		'[',
 		'\t',
 	} {
-		enc := m.encryptChar(c, reversed)
-		if enc != c {
-			t.Errorf("failed to encrypt '%c', want '%c', got '%c'", c, c, enc)
+		enc := m.encryptBytes([]byte{c}, reversed, EncryptOptions{})
+		if len(enc) != 1 || enc[0] != c {
+			t.Errorf("failed to encrypt '%c', want '%c', got '%s'", c, c, enc)
+		}
+	}
+}
+
+// TestEncryptPreserveCase tests that case is preserved by encryption and
+// decryption using PreserveCase.
+func TestEncryptPreserveCase(t *testing.T) {
+	opts := EncryptOptions{PreserveCase: true}
+	for i, test := range []struct {
+		message string
+		want    string
+	}{
+		{
+			message: "Hello, World!",
+			want:    "Sispr, Areko!",
+		},
+		{
+			message: "HELLO, world!",
+			want:    "SISPR, areko!",
+		},
+	} {
+		m, err := Read("../../test-data/config-1.json")
+		if err != nil {
+			t.Fatalf("test %d: failed to read machine: %v", i, err)
+		}
+		m.SetOptions(opts)
+
+		got, err := m.Encrypt(test.message)
+		if err != nil {
+			t.Fatalf("test %d: failed to encrypt: %v", i, err)
+		}
+		if got != test.want {
+			t.Errorf("test %d: incorrect encryption, want: %s, got: %s", i, test.want, got)
+		}
+
+		m.Reset()
+		decrypted, err := m.Encrypt(got)
+		if err != nil {
+			t.Fatalf("test %d: failed to decrypt: %v", i, err)
+		}
+		if decrypted != test.message {
+			t.Errorf("test %d: failed to decrypt: want %s, got %s", i, test.message, decrypted)
+		}
+	}
+}
+
+// TestEncryptUnicode tests that non-ASCII characters are returned without
+// change, and don't move the rotors.
+func TestEncryptUnicode(t *testing.T) {
+	for i, test := range []struct {
+		message string
+		want    string
+	}{
+		{
+			message: "Héllo, wörld!",
+			want:    "sépst, xöesa!",
+		},
+		{
+			message: "日本語 ß \xff",
+			want:    "日本語 ß \xff",
+		},
+	} {
+		m, err := Read("../../test-data/config-1.json")
+		if err != nil {
+			t.Fatalf("test %d: failed to read machine: %v", i, err)
+		}
+
+		got, err := m.Encrypt(test.message)
+		if err != nil {
+			t.Fatalf("test %d: failed to encrypt: %v", i, err)
+		}
+		if got != test.want {
+			t.Errorf("test %d: incorrect encryption, want: %q, got: %q", i, test.want, got)
+		}
+	}
+}
+
+// TestEncryptTransliterate tests encryption of non-ASCII letters using
+// Transliterate.
+func TestEncryptTransliterate(t *testing.T) {
+	opts := EncryptOptions{Transliterate: true}
+	for i, test := range []struct {
+		message  string
+		original string
+	}{
+		{
+			message:  "Héllo, wörld!",
+			original: "Hello, world!",
+		},
+		{
+			message:  "Straße, Ærø",
+			original: "Strasse, Aero",
+		},
+	} {
+		m, err := Read("../../test-data/config-2.json")
+		if err != nil {
+			t.Fatalf("test %d: failed to read machine: %v", i, err)
+		}
+		r := m.Clone()
+		m.SetOptions(opts)
+
+		got, err := m.Encrypt(test.message)
+		if err != nil {
+			t.Fatalf("test %d: failed to encrypt: %v", i, err)
+		}
+		want, err := r.Encrypt(test.original)
+		if err != nil {
+			t.Fatalf("test %d: failed to encrypt: %v", i, err)
+		}
+
+		if got != want {
+			t.Errorf("test %d: incorrect encryption, want: %s, got: %s", i, want, got)
 		}
 	}
 }
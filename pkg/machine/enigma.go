@@ -0,0 +1,228 @@
+package machine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Mode determines how a machine's rotors move and how signals pass through
+// them.
+type Mode int
+
+const (
+	// XenigmaMode is the default mode. Rotors move like an odometer, after
+	// each letter is encrypted, according to their steps and cycles.
+	XenigmaMode Mode = iota
+
+	// EnigmaMode models the historical Enigma I, M3, and M4 machines. Rotors
+	// have a ring setting and turnover notches, move before each letter is
+	// encrypted, and the middle rotor double-steps, so messages encrypted
+	// using a historical machine decrypt correctly.
+	EnigmaMode
+)
+
+// EnigmaRotors is the number of moving rotors of an Enigma machine. An M4
+// has an additional stationary Greek wheel.
+const EnigmaRotors = 3
+
+// String returns the name of the mode, as used in config files.
+func (mode Mode) String() string {
+	switch mode {
+	case XenigmaMode:
+		return "xenigma"
+	case EnigmaMode:
+		return "enigma"
+	}
+	return fmt.Sprintf("Mode(%d)", int(mode))
+}
+
+// ParseMode returns the mode with the given name, and an error if no such
+// mode exists. An empty name is parsed as XenigmaMode.
+func ParseMode(name string) (Mode, error) {
+	switch strings.ToLower(name) {
+	case "", "xenigma":
+		return XenigmaMode, nil
+	case "enigma":
+		return EnigmaMode, nil
+	}
+	return XenigmaMode, &ValidationError{Reason: "unknown mode", Got: strconv.Quote(name), Want: `"xenigma" or "enigma"`}
+}
+
+// NewEnigma creates and returns a new machine in EnigmaMode, and an error if
+// any of the given fields is invalid. Rotors are ordered from the rightmost
+// (fast) rotor, which is the first to receive the signal from the plugboard,
+// to the leftmost rotor, which is next to the reflector. Each rotor must be
+// created using NewEnigmaRotor, except for the Greek wheel of an M4, which
+// is a fourth, leftmost rotor created using NewStationaryRotor.
+func NewEnigma(rotors *Rotors, plugboard *Plugboard, reflector *Reflector) (*Machine, error) {
+	m := &Machine{
+		mode:      EnigmaMode,
+		alphabet:  LatinAlphabet,
+		rotors:    rotors,
+		plugboard: plugboard,
+		reflector: reflector,
+	}
+	if err := m.Verify(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// NewEnigmaRotor returns a new rotor for a machine in EnigmaMode, and an
+// error if given fields are invalid. position is the letter shown in the
+// rotor's window, ring is the ring setting (Ringstellung, 0 for "A" or 01),
+// and notches are the window letters at which the rotor turns over the rotor
+// to its left.
+func NewEnigmaRotor(pathways []int, position, ring int, notches []int) (*Rotor, error) {
+	if err := verifyRotor(pathways, position, DefaultStep, len(pathways)); err != nil {
+		return nil, err
+	}
+	if err := verifyRing(len(pathways), ring, notches); err != nil {
+		return nil, err
+	}
+
+	rotor := newRotor(append([]int(nil), pathways...), position, DefaultStep, len(pathways))
+	rotor.ring = ring
+	rotor.notches = append([]int(nil), notches...)
+	return rotor, nil
+}
+
+// NewStationaryRotor returns a new rotor that never moves, and an error if
+// given fields are invalid. Rotors after a stationary rotor are moved by the
+// rotor before it. ring is the ring setting, which must be 0 for machines
+// in XenigmaMode.
+func NewStationaryRotor(pathways []int, position, ring int) (*Rotor, error) {
+	if err := verifyRotor(pathways, position, DefaultStep, len(pathways)); err != nil {
+		return nil, err
+	}
+	if err := verifyRing(len(pathways), ring, nil); err != nil {
+		return nil, err
+	}
+
+	rotor := newRotor(append([]int(nil), pathways...), position, DefaultStep, len(pathways))
+	rotor.ring = ring
+	rotor.stationary = true
+	return rotor, nil
+}
+
+// verifyRing verifies a ring setting and turnover notches of a rotor of the
+// given size, and returns an error if they are invalid.
+func verifyRing(size, ring int, notches []int) error {
+	return verifyRingFields(size, ring, notches, strconv.Itoa).err()
+}
+
+// verifyRingFields is similar to verifyRing, but returns all invalid fields,
+// using name to describe positions.
+func verifyRingFields(size, ring int, notches []int, name func(int) string) (errs ValidationErrors) {
+	if ring < 0 || ring >= size {
+		errs = append(errs, &ValidationError{Path: "ring", Reason: "invalid ring setting", Got: strconv.Itoa(ring), Want: fmt.Sprintf("0 to %d", size-1)})
+	}
+
+	seen := make(map[int]int)
+	for i, notch := range notches {
+		path := fmt.Sprintf("notches[%d]", i)
+		if notch < 0 || notch >= size {
+			errs = append(errs, &ValidationError{Path: path, Reason: "invalid notch", Got: strconv.Itoa(notch), Want: fmt.Sprintf("0 to %d", size-1)})
+			continue
+		}
+		if j, ok := seen[notch]; ok {
+			errs = append(errs, &ValidationError{Path: path, Reason: fmt.Sprintf("duplicate notch, also used by notches[%d]", j), Got: name(notch)})
+			continue
+		}
+		seen[notch] = i
+	}
+	return errs
+}
+
+// verifyMode verifies that machine's components are compatible with its
+// mode and stepper, and returns an error if not.
+func verifyMode(mode Mode, stepper Stepper, rotors *Rotors) error {
+	var errs ValidationErrors
+	if verifier, ok := stepper.(interface{ verify([]*Rotor) error }); ok {
+		errs.add("", verifier.verify(rotors.rotors))
+	}
+
+	switch mode {
+	case XenigmaMode:
+		for i, rotor := range rotors.rotors {
+			if rotor.ring != 0 {
+				errs = append(errs, &ValidationError{
+					Path:   fmt.Sprintf("rotors[%d].ring", i),
+					Reason: "ring settings are only supported in enigma mode",
+					Got:    strconv.Itoa(rotor.ring),
+					Want:   "0",
+				})
+			}
+		}
+	case EnigmaMode:
+		if rotors.count != EnigmaRotors && rotors.count != EnigmaRotors+1 {
+			errs = append(errs, &ValidationError{
+				Path:   "rotors",
+				Reason: "invalid number of rotors for enigma mode",
+				Got:    strconv.Itoa(rotors.count),
+				Want:   fmt.Sprintf("%d or %d", EnigmaRotors, EnigmaRotors+1),
+			})
+			break
+		}
+		for i, rotor := range rotors.rotors {
+			path := fmt.Sprintf("rotors[%d]", i)
+			if i < EnigmaRotors && rotor.stationary {
+				errs = append(errs, &ValidationError{Path: path + ".stationary", Reason: "only the fourth rotor of an enigma machine can be stationary", Got: "true", Want: "false"})
+			}
+			if i >= EnigmaRotors && !rotor.stationary {
+				errs = append(errs, &ValidationError{Path: path + ".stationary", Reason: "the fourth rotor of an enigma machine must be stationary", Got: "false", Want: "true"})
+			}
+			if rotor.stationary {
+				continue
+			}
+
+			if rotor.step != DefaultStep || rotor.cycle != rotor.Size() {
+				errs = append(errs, &ValidationError{
+					Path:   path,
+					Reason: "invalid step and cycle for enigma mode",
+					Got:    fmt.Sprintf("step %d and cycle %d", rotor.step, rotor.cycle),
+					Want:   fmt.Sprintf("step %d and cycle %d", DefaultStep, rotor.Size()),
+				})
+			}
+		}
+	default:
+		errs = append(errs, &ValidationError{Path: "mode", Reason: "unknown mode", Got: strconv.Itoa(int(mode))})
+	}
+	return errs.err()
+}
+
+// atNotch returns true if a turnover notch of the rotor is at its current
+// position.
+func (r *Rotor) atNotch() bool {
+	for _, notch := range r.notches {
+		if r.position == notch {
+			return true
+		}
+	}
+	return false
+}
+
+// encryptEnigma encrypts one character, represented by its position in the
+// alphabet, the way an Enigma machine does. Rotors move before the
+// character is encrypted, and each rotor's wiring is shifted by the
+// difference between its position and its ring setting.
+func (m *Machine) encryptEnigma(char int, reversed [][]int) int {
+	size := m.alphabet.Size()
+	m.Stepper().Step(m.rotors.rotors)
+
+	encrypted := m.plugboard.PlugIn(char)
+	for _, rotor := range m.rotors.rotors {
+		shift := (rotor.position - rotor.ring + size) % size
+		encrypted = (rotor.pathways[(encrypted+shift)%size] - shift + size) % size
+	}
+
+	encrypted = m.reflector.Reflect(encrypted)
+	for i := m.rotors.count - 1; i >= 0; i-- {
+		rotor := m.rotors.rotors[i]
+		shift := (rotor.position - rotor.ring + size) % size
+		encrypted = (reversed[i][(encrypted+shift)%size] - shift + size) % size
+	}
+
+	return m.plugboard.PlugOut(encrypted)
+}
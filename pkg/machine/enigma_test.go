@@ -0,0 +1,228 @@
+package machine
+
+import (
+	"strings"
+	"testing"
+)
+
+// newTestEnigma returns an Enigma machine using the given reflector, rotors,
+// ring settings, and positions, all listed from left to right as written in
+// historical key sheets, and a plugboard with the given space separated
+// pairs.
+func newTestEnigma(t *testing.T, reflectorName, wheels, rings, positions, pairs string) *Machine {
+	t.Helper()
+
+	names := strings.Fields(wheels)
+	rotors := make([]*Rotor, len(names))
+	for i, name := range names {
+		j := len(names) - 1 - i // Rightmost rotor is the first.
+		rotor, err := NewHistoricalRotor(name, int(positions[i]-'A'), int(rings[i]-'A'))
+		if err != nil {
+			t.Fatalf("failed to create rotor %s: %v", name, err)
+		}
+		rotors[j] = rotor
+	}
+	r, err := NewRotors(rotors)
+	if err != nil {
+		t.Fatalf("failed to create rotors: %v", err)
+	}
+
+	reflector, err := NewHistoricalReflector(reflectorName)
+	if err != nil {
+		t.Fatalf("failed to create reflector: %v", err)
+	}
+
+	connections := make(map[int]int)
+	for i := 0; i < 26; i++ {
+		connections[i] = i
+	}
+	for _, pair := range strings.Fields(pairs) {
+		a, b := int(pair[0]-'A'), int(pair[1]-'A')
+		connections[a], connections[b] = b, a
+	}
+	plugboard, err := NewPlugboard(connections)
+	if err != nil {
+		t.Fatalf("failed to create plugboard: %v", err)
+	}
+
+	m, err := NewEnigma(r, plugboard, reflector)
+	if err != nil {
+		t.Fatalf("failed to create machine: %v", err)
+	}
+	return m
+}
+
+// TestEnigmaVectors tests that EnigmaMode reproduces published Enigma I, M3,
+// and M4 test vectors.
+func TestEnigmaVectors(t *testing.T) {
+	for i, test := range []struct {
+		reflector string
+		wheels    string
+		rings     string
+		positions string
+		pairs     string
+		message   string
+		encrypted string
+	}{
+		{
+			reflector: "UKW-B",
+			wheels:    "I II III",
+			rings:     "AAA",
+			positions: "AAA",
+			message:   "aaaaa",
+			encrypted: "bdzgo",
+		},
+		{
+			// Operation Barbarossa, 1941.
+			reflector: "UKW-B",
+			wheels:    "II IV V",
+			rings:     "BUL",
+			positions: "BLA",
+			pairs:     "AV BS CG DL FU HZ IN KM OW RX",
+			message:   "edpudnrgyszrcxnuytpomrmbofktbzrezkmlxlvefgueysiozveqmikubpmmylklttdeismdicagykuactcdomohwxmuuiaubstslrnbzszwnrfxwfyssxjzvijhidishprklkayupadtxqspinqmatlpifsvkdasctacdpbopvhjk",
+			encrypted: "aufklxabteilungxvonxkurtinowaxkurtinowaxnordwestlxsebezxsebezxuaffliegerstraszeriqtungxdubrowkixdubrowkixopotschkaxopotschkaxumxeinsaqtdreinullxuhrangetretenxangriffxinfxrgtx",
+		},
+		{
+			// U-534, M4 with Greek wheel Beta.
+			reflector: "UKW-B-thin",
+			wheels:    "Beta II IV I",
+			rings:     "AAAV",
+			positions: "VJNA",
+			pairs:     "AT BL DF GJ HM NW OP QY RZ VX",
+			message:   "nczwvusxpnyminhzxmqxsfwxwlkjahshnmcoccakuqpmkcsmhkseinjusblkiosxckubhmllxcsjusrrdvkohulxwccbgvliyxeoahxrhkkfvdrewezlxobafgyujqukgrtvukameurbveksuhhvoyhabcjwmaklfklmyfvnrizrvvrtkofdanjmolbgffleoprgtflvrhowopbekvwmuqfmpwparmfhagkxiibg",
+			encrypted: "vonvonjlooksjhffttteinseinsdreizwoyyqnnsneuninhaltxxbeiangriffunterwassergedruecktywabosxletztergegnerstandnulachtdreinuluhrmarquantonjotaneunachtseyhsdreiyzwozwonulgradyachtsmystossenachxeknsviermbfaelltynnnnnnooovierysichteinsnull",
+		},
+	} {
+		m := newTestEnigma(t, test.reflector, test.wheels, test.rings, test.positions, test.pairs)
+		encrypted, err := m.Encrypt(test.message)
+		if err != nil {
+			t.Errorf("test %d: failed to encrypt: %v", i, err)
+		}
+		if encrypted != test.encrypted {
+			t.Errorf("test %d: incorrect encryption, want: %s, got: %s", i, test.encrypted, encrypted)
+		}
+
+		m.Reset()
+		decrypted, err := m.Encrypt(encrypted)
+		if err != nil {
+			t.Errorf("test %d: failed to decrypt: %v", i, err)
+		}
+		if decrypted != test.message {
+			t.Errorf("test %d: incorrect decryption, want: %s, got: %s", i, test.message, decrypted)
+		}
+	}
+}
+
+// TestEnigmaDoubleStep tests the double-step anomaly of the middle rotor.
+func TestEnigmaDoubleStep(t *testing.T) {
+	m := newTestEnigma(t, "UKW-B", "I II III", "AAA", "ADU", "")
+	for i, want := range []string{"ADV", "AEW", "BFX", "BFY"} {
+		m.Advance(1)
+
+		var got []byte
+		for j := m.rotors.count - 1; j >= 0; j-- {
+			got = append(got, byte('A'+m.rotors.rotors[j].position))
+		}
+		if string(got) != want {
+			t.Errorf("test %d: incorrect window, want: %s, got: %s", i, want, got)
+		}
+	}
+}
+
+// TestEnigmaSeekTo tests that seeking in EnigmaMode produces the same
+// encryption as encrypting.
+func TestEnigmaSeekTo(t *testing.T) {
+	m := newTestEnigma(t, "UKW-B", "I II III", "AAA", "ADT", "")
+
+	message := strings.Repeat("enigma", 200)
+	encrypted, err := m.Encrypt(message)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	m.SeekTo(1000)
+	got, err := m.Encrypt(message[1000:])
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if want := encrypted[1000:]; got != want {
+		t.Errorf("incorrect encryption, want: %s, got: %s", want, got)
+	}
+}
+
+// TestEnigmaConfig tests reading Enigma machines from config files.
+func TestEnigmaConfig(t *testing.T) {
+	for i, test := range []struct {
+		path      string
+		message   string
+		encrypted string
+	}{
+		{
+			path:      "../../test-data/enigma-config-1.json",
+			message:   "AAAAA",
+			encrypted: "bdzgo",
+		},
+		{
+			path:      "../../test-data/enigma-config-2.json",
+			message:   "NCZWVUSXPNYM",
+			encrypted: "vonvonjlooks",
+		},
+		{
+			path:      "../../test-data/enigma-config-1.toml",
+			message:   "AAAAA",
+			encrypted: "bdzgo",
+		},
+		{
+			path:      "../../test-data/enigma-config-2.yaml",
+			message:   "NCZWVUSXPNYM",
+			encrypted: "vonvonjlooks",
+		},
+	} {
+		m, err := Read(test.path)
+		if err != nil {
+			t.Errorf("test %d: failed to read machine: %v", i, err)
+			continue
+		}
+		if m.Mode() != EnigmaMode {
+			t.Errorf("test %d: incorrect mode, want: %v, got: %v", i, EnigmaMode, m.Mode())
+		}
+
+		encrypted, err := m.Encrypt(test.message)
+		if err != nil {
+			t.Errorf("test %d: failed to encrypt: %v", i, err)
+		}
+		if encrypted != test.encrypted {
+			t.Errorf("test %d: incorrect encryption, want: %s, got: %s", i, test.encrypted, encrypted)
+		}
+	}
+}
+
+// TestEnigmaVerify tests that components incompatible with a machine's mode
+// are rejected.
+func TestEnigmaVerify(t *testing.T) {
+	m := newTestEnigma(t, "UKW-B", "I II III", "BBB", "AAA", "")
+
+	if _, err := New(m.rotors, m.plugboard, m.reflector); err == nil {
+		t.Errorf("accepted rotors with ring settings in xenigma mode")
+	}
+
+	rotors, err := NewRotors(m.rotors.rotors[:2])
+	if err != nil {
+		t.Fatalf("failed to create rotors: %v", err)
+	}
+	if _, err := NewEnigma(rotors, m.plugboard, m.reflector); err == nil {
+		t.Errorf("accepted enigma machine with 2 rotors")
+	}
+
+	greek, err := NewHistoricalRotor("Gamma", 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create rotor: %v", err)
+	}
+	rotors, err = NewRotors([]*Rotor{greek, m.rotors.rotors[1], m.rotors.rotors[2]})
+	if err != nil {
+		t.Fatalf("failed to create rotors: %v", err)
+	}
+	if _, err := NewEnigma(rotors, m.plugboard, m.reflector); err == nil {
+		t.Errorf("accepted stationary fast rotor")
+	}
+}
@@ -0,0 +1,236 @@
+package machine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError describes an invalid field of a machine or a config file.
+// Path locates the field using the names of config fields, for example
+// "rotors[3].pathways[7]" is the eighth pathway of the fourth rotor, and
+// `plugboard.connections["a"]` is the plugboard connection of "a". Rotors
+// are indexed from the first rotor to receive the signal. Errors returned
+// when parsing a config file are positioned at the field in the file, or at
+// the closest enclosing field if the field itself isn't in the file.
+type ValidationError struct {
+	Path   string // Path of the invalid field, empty for the whole machine.
+	Reason string // Why the field is invalid.
+	Got    string // The invalid value, if any.
+	Want   string // A description of valid values, if known.
+	Line   int    // Line of the field in the config file, 0 if unknown.
+	Column int    // Column of the field in the config file, 0 if unknown.
+}
+
+// ValidationErrors is a list of all invalid fields of a machine or a config
+// file. Functions that verify a machine return ValidationErrors, so that
+// every problem is reported at once. errors.As can be used to retrieve
+// either the list, or its first *ValidationError.
+type ValidationErrors []*ValidationError
+
+// Error returns the path, reason, and values of the error.
+func (e *ValidationError) Error() string {
+	message := e.Reason
+	if e.Path != "" {
+		message = e.Path + ": " + message
+	}
+
+	switch {
+	case e.Got != "" && e.Want != "":
+		message += fmt.Sprintf(" (got %s, want %s)", e.Got, e.Want)
+	case e.Got != "":
+		message += fmt.Sprintf(" (got %s)", e.Got)
+	case e.Want != "":
+		message += fmt.Sprintf(" (want %s)", e.Want)
+	}
+	return message
+}
+
+// Error returns all errors of the list separated by semicolons.
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d invalid fields: %s", len(e), strings.Join(messages, "; "))
+}
+
+// As sets target to the first error of the list if target is a
+// **ValidationError, and returns true if it was set.
+func (e ValidationErrors) As(target interface{}) bool {
+	if first, ok := target.(**ValidationError); ok && len(e) != 0 {
+		*first = e[0]
+		return true
+	}
+	return false
+}
+
+// add appends err to the list, prefixing the paths of validation errors
+// with path. Other errors are appended as a validation error of path, and
+// nil errors are ignored.
+func (e *ValidationErrors) add(path string, err error) {
+	switch err := err.(type) {
+	case nil:
+	case *ValidationError:
+		*e = append(*e, &ValidationError{
+			Path:   joinPath(path, err.Path),
+			Reason: err.Reason,
+			Got:    err.Got,
+			Want:   err.Want,
+			Line:   err.Line,
+			Column: err.Column,
+		})
+	case ValidationErrors:
+		for _, single := range err {
+			e.add(path, single)
+		}
+	default:
+		*e = append(*e, &ValidationError{Path: path, Reason: err.Error()})
+	}
+}
+
+// err returns the list as an error, or nil if the list is empty.
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// joinPath joins the path of a field with the path of one of its fields.
+func joinPath(parent, child string) string {
+	switch {
+	case parent == "":
+		return child
+	case child == "":
+		return parent
+	case strings.HasPrefix(child, "["):
+		return parent + child
+	}
+	return parent + "." + child
+}
+
+// verifyPermutation verifies that values of a slice of size n contain
+// numbers 0 through n - 1, and returns an error for each value that is out
+// of range or duplicate. path is the name of the slice, noun is the name of
+// its values, index describes indices of the slice in paths, and name
+// describes values in errors.
+func verifyPermutation(path, noun string, values []int, index, name func(int) string) ValidationErrors {
+	if zeroToNSlice(values) {
+		return nil
+	}
+
+	size := len(values)
+	used := make(map[int]int)
+	for i, value := range values {
+		if _, ok := used[value]; !ok && value >= 0 && value < size {
+			used[value] = i
+		}
+	}
+	want := describeMissing(size, used, name)
+
+	var errs ValidationErrors
+	for i, value := range values {
+		switch {
+		case value < 0 || value >= size:
+			errs = append(errs, &ValidationError{
+				Path:   fmt.Sprintf("%s[%s]", path, index(i)),
+				Reason: noun + " out of range",
+				Got:    strconv.Itoa(value),
+				Want:   want,
+			})
+		case used[value] != i:
+			errs = append(errs, &ValidationError{
+				Path:   fmt.Sprintf("%s[%s]", path, index(i)),
+				Reason: fmt.Sprintf("duplicate %s, also used by %s[%s]", noun, path, index(used[value])),
+				Got:    name(value),
+				Want:   want,
+			})
+		}
+	}
+	return errs
+}
+
+// verifyConnectionMap verifies that connections map elements 0 through n-1
+// to each other symmetrically, where n is the number of connections, and
+// returns an error for each invalid connection. name describes elements in
+// paths and errors.
+func verifyConnectionMap(connections map[int]int, name func(int) string) ValidationErrors {
+	size := len(connections)
+	if size == 0 {
+		return ValidationErrors{{Path: "connections", Reason: "no connections given"}}
+	}
+	if zeroToN(connections, size) && isSymmetric(connections) {
+		return nil
+	}
+
+	keys := make([]int, 0, size)
+	for key := range connections {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+
+	var errs ValidationErrors
+	for _, key := range keys {
+		if key < 0 || key >= size {
+			errs = append(errs, &ValidationError{
+				Path:   fmt.Sprintf("connections[%d]", key),
+				Reason: "connection key out of range",
+				Got:    strconv.Itoa(key),
+				Want:   fmt.Sprintf("0 to %d", size-1),
+			})
+		}
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
+	values := make([]int, size)
+	for key, value := range connections {
+		values[key] = value
+	}
+	errs = verifyPermutation("connections", "connection", values, name, name)
+	if len(errs) != 0 {
+		return errs
+	}
+
+	inverse := make([]int, size)
+	for key, value := range values {
+		inverse[value] = key
+	}
+	for key, value := range values {
+		if values[value] != key {
+			errs = append(errs, &ValidationError{
+				Path:   fmt.Sprintf("connections[%s]", name(key)),
+				Reason: fmt.Sprintf("connections are not symmetric, %s is connected to %s", name(value), name(values[value])),
+				Got:    name(value),
+				Want:   name(inverse[key]),
+			})
+		}
+	}
+	return errs
+}
+
+// describeMissing returns a description of the numbers 0 through size-1
+// that aren't used.
+func describeMissing(size int, used map[int]int, name func(int) string) string {
+	var missing []string
+	for i := 0; i < size; i++ {
+		if _, ok := used[i]; !ok {
+			missing = append(missing, name(i))
+		}
+	}
+
+	switch len(missing) {
+	case 0:
+		return ""
+	case 1:
+		return missing[0]
+	}
+	return "one of " + strings.Join(missing, ", ")
+}
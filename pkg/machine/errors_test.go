@@ -0,0 +1,117 @@
+package machine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestValidationErrors tests that every invalid field of a config is
+// reported with its path.
+func TestValidationErrors(t *testing.T) {
+	pathways := strings.Split("ekmflgdqvzntowyhxuspaibrcj", "")
+	pathways[7] = "e"
+
+	config := fmt.Sprintf(`{
+		"mode": "xenigma",
+		"stepping": "clockwork",
+		"rotors": [
+			{"wiring": "I", "position": "a", "step": 1, "cycle": 26},
+			{"pathways": ["%s"], "position": "1", "step": 1, "cycle": 26}
+		],
+		"reflector": "UKW-Z",
+		"plugboard": {"pairs": "ab bc"}
+	}`, strings.Join(pathways, `", "`))
+
+	_, err := Parse([]byte(config))
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("error is not ValidationErrors: %v", err)
+	}
+
+	var paths []string
+	for _, err := range errs {
+		paths = append(paths, err.Path)
+	}
+	want := []string{
+		"stepping",
+		"rotors[1].pathways[7]",
+		"rotors[1].position",
+		"plugboard.pairs",
+		"reflector",
+	}
+	if diff := cmp.Diff(want, paths); diff != "" {
+		t.Errorf("incorrect paths (-want +got):\n%s", diff)
+	}
+
+	var first *ValidationError
+	if !errors.As(err, &first) || first != errs[0] {
+		t.Errorf("errors.As didn't return the first error, got: %v", first)
+	}
+
+	duplicate := &ValidationError{
+		Path:   "rotors[1].pathways[7]",
+		Reason: "duplicate pathway, also used by pathways[0]",
+		Got:    `"e"`,
+		Want:   `"q"`,
+		Line:   6,
+		Column: 53,
+	}
+	if diff := cmp.Diff(duplicate, errs[1]); diff != "" {
+		t.Errorf("incorrect pathway error (-want +got):\n%s", diff)
+	}
+}
+
+// TestValidationErrorsAPI tests errors returned by constructors.
+func TestValidationErrorsAPI(t *testing.T) {
+	for i, test := range []struct {
+		err  error
+		want ValidationErrors
+	}{
+		{
+			err: func() error {
+				_, err := NewRotor([]int{0, 1, 1, 3}, 1, 2, 3)
+				return err
+			}(),
+			want: ValidationErrors{
+				{Path: "pathways[2]", Reason: "duplicate pathway, also used by pathways[1]", Got: "1", Want: "2"},
+				{Path: "position", Reason: "invalid position", Got: "1", Want: "a multiple of step 2"},
+				{Path: "cycle", Reason: "cycle and step are incompatible, some collisions may occur", Got: "step 2 and cycle 3", Want: "step times cycle dividing 4"},
+			},
+		},
+		{
+			err: func() error {
+				_, err := NewReflector(map[int]int{0: 1, 1: 2, 2: 3, 3: 0})
+				return err
+			}(),
+			want: ValidationErrors{
+				{Path: "connections[0]", Reason: "connections are not symmetric, 1 is connected to 2", Got: "1", Want: "3"},
+				{Path: "connections[1]", Reason: "connections are not symmetric, 2 is connected to 3", Got: "2", Want: "0"},
+				{Path: "connections[2]", Reason: "connections are not symmetric, 3 is connected to 0", Got: "3", Want: "1"},
+				{Path: "connections[3]", Reason: "connections are not symmetric, 0 is connected to 1", Got: "0", Want: "2"},
+			},
+		},
+		{
+			err: func() error {
+				_, err := NewAlphabet("abcdb")
+				return err
+			}(),
+			want: ValidationErrors{
+				{Path: "[4]", Reason: "duplicate character, also at [1]", Got: "'b'"},
+				{Reason: "alphabet must contain an even number of characters", Got: "5"},
+			},
+		},
+	} {
+		var errs ValidationErrors
+		if !errors.As(test.err, &errs) {
+			t.Errorf("test %d: error is not ValidationErrors: %v", i, test.err)
+			continue
+		}
+		if diff := cmp.Diff(test.want, errs); diff != "" {
+			t.Errorf("test %d: mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
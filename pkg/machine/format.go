@@ -0,0 +1,229 @@
+package machine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format of a config file.
+type Format int
+
+// Supported config file formats.
+const (
+	JSON Format = iota
+	YAML
+	TOML
+)
+
+// String returns the name of the format.
+func (f Format) String() string {
+	switch f {
+	case JSON:
+		return "JSON"
+	case YAML:
+		return "YAML"
+	case TOML:
+		return "TOML"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// tomlKey matches the first line of a TOML file, which is either a table
+// header or a key/value pair.
+var tomlKey = regexp.MustCompile(`^(\[|[A-Za-z0-9_"'.-]+\s*=)`)
+
+// DetectFormat returns the format of the given config contents. Contents
+// that start with "{" are JSON, contents whose first line is a TOML table
+// header or key/value pair are TOML, and all other contents are YAML.
+// Empty lines and comments are skipped.
+func DetectFormat(contents []byte) Format {
+	trimmed := bytes.TrimSpace(contents)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return JSON
+	}
+
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if tomlKey.MatchString(line) {
+			return TOML
+		}
+		break
+	}
+	return YAML
+}
+
+// formatOf returns the format of a config file based on the extension of
+// its path, and false if the extension isn't known.
+func formatOf(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON, true
+	case ".yaml", ".yml":
+		return YAML, true
+	case ".toml":
+		return TOML, true
+	}
+	return JSON, false
+}
+
+// ParseFormat parses given contents of the given format into a Machine, and
+// returns a pointer to it, and an error in case of invalid fields. Errors
+// are ValidationErrors positioned at the invalid fields in contents.
+func ParseFormat(contents []byte, format Format) (*Machine, error) {
+	jsonM, err := unmarshalFormat(contents, format)
+	if err != nil {
+		return nil, syntaxErrors(err, contents, format)
+	}
+
+	m, err := parseMachine(jsonM)
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		locate(errs, contents, format)
+	}
+	return m, err
+}
+
+// unmarshalFormat unmarshals given contents of the given format into a
+// jsonMachine, without parsing its fields.
+func unmarshalFormat(contents []byte, format Format) (*jsonMachine, error) {
+	jsonM := new(jsonMachine)
+	var err error
+	switch format {
+	case JSON:
+		err = json.Unmarshal(contents, jsonM)
+	case YAML:
+		err = yaml.Unmarshal(contents, jsonM)
+	case TOML:
+		err = toml.Unmarshal(contents, jsonM)
+	default:
+		err = fmt.Errorf("unknown format %v", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jsonM, nil
+}
+
+// marshalFormat returns the contents of a config file of the given format
+// describing Machine m.
+func marshalFormat(m *Machine, format Format) ([]byte, error) {
+	mToJSON, err := marshalMachine(m)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case JSON:
+		return json.MarshalIndent(mToJSON, "", "\t")
+	case YAML:
+		return marshalYAML(mToJSON)
+	case TOML:
+		buffer := new(bytes.Buffer)
+		err := toml.NewEncoder(buffer).Encode(mToJSON)
+		return buffer.Bytes(), err
+	}
+	return nil, fmt.Errorf("unknown format %v", format)
+}
+
+// marshalYAML returns a jsonMachine as YAML. The yaml package writes some
+// strings containing line breaks as block scalars that are read back
+// differently, for example "\n" is read back as an empty string, so the
+// machine is converted to a YAML node through JSON, which YAML is a superset
+// of, and strings containing control characters are kept double-quoted.
+func marshalYAML(jsonM *jsonMachine) ([]byte, error) {
+	contents, err := json.Marshal(jsonM)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON doesn't escape all control characters, but YAML requires them
+	// to be escaped. Compact JSON contains control characters only inside
+	// of strings, so all of them can be escaped.
+	escaped := new(strings.Builder)
+	for _, char := range string(contents) {
+		if unicode.IsControl(char) {
+			fmt.Fprintf(escaped, "\\u%04x", char)
+		} else {
+			escaped.WriteRune(char)
+		}
+	}
+
+	node := new(yaml.Node)
+	if err := yaml.Unmarshal([]byte(escaped.String()), node); err != nil {
+		return nil, err
+	}
+	restyle(node)
+	return yaml.Marshal(node)
+}
+
+// restyle sets the style of a YAML node and its children to the default
+// block style, except for scalars that contain control characters, which
+// are double-quoted.
+func restyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && strings.IndexFunc(node.Value, unicode.IsControl) != -1 {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		restyle(child)
+	}
+}
+
+// UnmarshalYAML unmarshals either a wiring name or a connections map into
+// the reflector.
+func (r *jsonReflector) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return value.Decode(&r.Name)
+	}
+
+	type connections jsonReflector
+	return value.Decode((*connections)(r))
+}
+
+// UnmarshalTOML unmarshals either a wiring name, or a table containing a
+// wiring name or a connections map into the reflector.
+func (r *jsonReflector) UnmarshalTOML(data interface{}) error {
+	switch value := data.(type) {
+	case string:
+		r.Name = value
+		return nil
+	case map[string]interface{}:
+		for key, field := range value {
+			switch key {
+			case "name":
+				name, ok := field.(string)
+				if !ok {
+					return fmt.Errorf("invalid reflector name %v", field)
+				}
+				r.Name = name
+			case "connections":
+				connections, ok := field.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("invalid reflector connections %v", field)
+				}
+				r.Connections = make(map[string]string)
+				for k, v := range connections {
+					str, ok := v.(string)
+					if !ok {
+						return fmt.Errorf("invalid reflector value %v", v)
+					}
+					r.Connections[k] = str
+				}
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid reflector %v", data)
+}
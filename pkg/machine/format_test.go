@@ -0,0 +1,127 @@
+package machine
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestDetectFormat tests detecting the format of config contents.
+func TestDetectFormat(t *testing.T) {
+	for i, test := range []struct {
+		contents string
+		want     Format
+	}{
+		{contents: `{"rotors": []}`, want: JSON},
+		{contents: "\n\t{\n}", want: JSON},
+		{contents: "mode: enigma\n", want: YAML},
+		{contents: "# comment\nrotors:\n  - wiring: I\n", want: YAML},
+		{contents: "---\nmode: enigma\n", want: YAML},
+		{contents: `mode = "enigma"`, want: TOML},
+		{contents: "# comment\n\n[[rotors]]\nwiring = \"I\"\n", want: TOML},
+		{contents: "[plugboard]\npairs = \"ab\"\n", want: TOML},
+	} {
+		if got := DetectFormat([]byte(test.contents)); got != test.want {
+			t.Errorf("test %d: incorrect format, want: %v, got: %v", i, test.want, got)
+		}
+	}
+}
+
+// TestReadAndWriteFormats tests that machines written as JSON, YAML, and
+// TOML are read back unchanged, and that each file is written in the format
+// of its extension.
+func TestReadAndWriteFormats(t *testing.T) {
+	err := os.MkdirAll("../../test-data/generate", os.ModePerm)
+	if err != nil {
+		t.Fatal("failed to create test-data/generate")
+	}
+
+	enigma, err := Read("../../test-data/enigma-config-2.json")
+	if err != nil {
+		t.Fatalf("failed to read enigma machine: %v", err)
+	}
+
+	unexported := cmp.AllowUnexported(
+		Machine{},
+		Rotors{},
+		Rotor{},
+		Plugboard{},
+		Reflector{},
+	)
+
+	for i, m := range []*Machine{
+		Generate(5),
+		enigma,
+		GenerateWithAlphabet(4, PrintableAlphabet, rand.NewSource(0)),
+		GenerateWithAlphabet(3, byteAlphabet, rand.NewSource(1)),
+	} {
+		for _, test := range []struct {
+			path   string
+			format Format
+		}{
+			{path: "../../test-data/generate/generated.json", format: JSON},
+			{path: "../../test-data/generate/generated.yaml", format: YAML},
+			{path: "../../test-data/generate/generated.yml", format: YAML},
+			{path: "../../test-data/generate/generated.toml", format: TOML},
+		} {
+			if err := Write(m, test.path); err != nil {
+				t.Errorf("test %d: failed to write %s: %v", i, test.path, err)
+				continue
+			}
+
+			contents, err := ioutil.ReadFile(test.path)
+			if err != nil {
+				t.Errorf("test %d: failed to read %s: %v", i, test.path, err)
+				continue
+			}
+			if got := DetectFormat(contents); got != test.format {
+				t.Errorf("test %d: incorrect format of %s, want: %v, got: %v", i, test.path, test.format, got)
+			}
+
+			r, err := Read(test.path)
+			if err != nil {
+				t.Errorf("test %d: failed to read %s: %v", i, test.path, err)
+				continue
+			}
+			if diff := cmp.Diff(*m, *r, unexported); diff != "" {
+				t.Errorf("test %d: %s mismatch (-want +got):\n%s", i, test.path, diff)
+			}
+		}
+	}
+}
+
+// TestWriteKeepsFormat tests that writing to an existing file without a
+// known extension keeps the file's format.
+func TestWriteKeepsFormat(t *testing.T) {
+	err := os.MkdirAll("../../test-data/generate", os.ModePerm)
+	if err != nil {
+		t.Fatal("failed to create test-data/generate")
+	}
+
+	const path = "../../test-data/generate/xenigma.conf"
+	for i, format := range []Format{YAML, TOML, JSON} {
+		contents, err := marshalFormat(Generate(3), format)
+		if err != nil {
+			t.Fatalf("test %d: failed to marshal: %v", i, err)
+		}
+		if err := ioutil.WriteFile(path, contents, 0664); err != nil {
+			t.Fatalf("test %d: failed to write: %v", i, err)
+		}
+
+		if err := Write(Generate(3), path); err != nil {
+			t.Errorf("test %d: failed to write: %v", i, err)
+			continue
+		}
+		contents, err = ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("test %d: failed to read: %v", i, err)
+			continue
+		}
+		if got := DetectFormat(contents); got != format {
+			t.Errorf("test %d: incorrect format, want: %v, got: %v", i, format, got)
+		}
+	}
+}
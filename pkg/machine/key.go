@@ -0,0 +1,412 @@
+package machine
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// keyVersion is the version prefix of key strings.
+const keyVersion = "X1"
+
+// Fields of a key string, in the order they are written.
+var keyFields = []string{
+	"mode",
+	"stepping",
+	"alpha",
+	"rotors",
+	"pos",
+	"ring",
+	"steps",
+	"notch",
+	"stationary",
+	"plug",
+	"refl",
+}
+
+// KeyString returns machine's configuration as a compact, single-line key
+// string, and an error if machine's fields are invalid, or if it uses a
+// stepper that isn't built-in. A key string contains the same settings as
+// a config file, and can be parsed using ParseKey. For example, an Enigma I
+// with rotors I, II, and III is written as:
+//
+//	X1:mode=enigma;rotors=III,II,I;pos=aaa;plug=ab cd;refl=UKW-B
+//
+// Fields are separated by semicolons, and are written only if they differ
+// from their defaults. Lists of rotor settings are ordered like the rotors
+// of the machine, and rotors with a historical wiring are written by name.
+func (m *Machine) KeyString() (string, error) {
+	jsonM, err := marshalMachine(m)
+	if err != nil {
+		return "", err
+	}
+
+	fields := make(map[string]string)
+	if jsonM.Mode != "" {
+		fields["mode"] = jsonM.Mode
+	}
+	if jsonM.Stepping != "" {
+		fields["stepping"] = jsonM.Stepping
+	}
+	if jsonM.Alphabet != "" {
+		fields["alpha"] = escapeKey(jsonM.Alphabet)
+	}
+
+	size := m.alphabet.Size()
+	var wirings, steps, notches, stationary []string
+	var positions, rings strings.Builder
+	var customSteps, customRings, customNotches, customStationary bool
+	for i, rotor := range jsonM.Rotors {
+		if rotor.Wiring != "" {
+			wirings = append(wirings, rotor.Wiring)
+		} else {
+			wirings = append(wirings, escapeKey(strings.Join(rotor.Pathways, "")))
+		}
+
+		positions.WriteString(escapeKey(rotor.Position))
+		rings.WriteString(escapeKey(intToStr(m.rotors.rotors[i].ring, m.alphabet)))
+		customRings = customRings || m.rotors.rotors[i].ring != 0
+
+		steps = append(steps, fmt.Sprintf("%d:%d", rotor.Step, rotor.Cycle))
+		customSteps = customSteps || rotor.Step != DefaultStep || rotor.Cycle != size
+
+		notches = append(notches, escapeKey(strings.Join(rotor.Notches, "")))
+		customNotches = customNotches || rotor.Notches != nil
+
+		switch {
+		case rotor.Stationary == nil:
+			stationary = append(stationary, "")
+		case *rotor.Stationary:
+			stationary = append(stationary, "1")
+		default:
+			stationary = append(stationary, "0")
+		}
+		customStationary = customStationary || rotor.Stationary != nil
+	}
+
+	fields["rotors"] = strings.Join(wirings, ",")
+	fields["pos"] = positions.String()
+	if customRings {
+		fields["ring"] = rings.String()
+	}
+	if customSteps {
+		fields["steps"] = strings.Join(steps, ",")
+	}
+	if customNotches {
+		fields["notch"] = strings.Join(notches, ",")
+	}
+	if customStationary {
+		fields["stationary"] = strings.Join(stationary, ",")
+	}
+
+	var pairs []string
+	for i := 0; i < size; i++ {
+		if j := m.plugboard.connections[i]; i < j {
+			pairs = append(pairs, escapeKey(intToStr(i, m.alphabet)+intToStr(j, m.alphabet)))
+		}
+	}
+	if len(pairs) != 0 {
+		fields["plug"] = strings.Join(pairs, " ")
+	}
+
+	if jsonM.Reflector.Name != "" {
+		fields["refl"] = jsonM.Reflector.Name
+	} else {
+		var reflections strings.Builder
+		for i := 0; i < size; i++ {
+			reflections.WriteString(intToStr(m.reflector.connections[i], m.alphabet))
+		}
+		fields["refl"] = escapeKey(reflections.String())
+	}
+
+	written := make([]string, 0, len(fields))
+	for _, name := range keyFields {
+		if value, ok := fields[name]; ok {
+			written = append(written, name+"="+value)
+		}
+	}
+	return keyVersion + ":" + strings.Join(written, ";"), nil
+}
+
+// ParseKey parses a key string created using KeyString into a Machine, and
+// returns an error if the key string is malformed or machine's fields are
+// invalid. Fields can be given in any order, and letters are not case
+// sensitive. Rotors, positions, and reflector are required.
+func ParseKey(key string) (*Machine, error) {
+	key = strings.TrimSpace(key)
+	colon := strings.Index(key, ":")
+	if colon < 0 || key[:colon] != keyVersion {
+		return nil, fmt.Errorf("invalid key: expected %s prefix", keyVersion)
+	}
+
+	fields, err := splitKey(key[colon+1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+
+	m, err := parseKeyFields(fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return m, m.Verify()
+}
+
+// splitKey splits the body of a key string into named fields, and returns
+// an error if a field is malformed, unknown, or repeated.
+func splitKey(body string) (map[string]string, error) {
+	fields := make(map[string]string)
+	for _, field := range strings.Split(body, ";") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+
+		equals := strings.Index(field, "=")
+		if equals < 0 {
+			return nil, fmt.Errorf("field %q is not of the form name=value", field)
+		}
+
+		name, value := strings.TrimSpace(field[:equals]), strings.TrimSpace(field[equals+1:])
+		if !knownKeyField(name) {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if _, ok := fields[name]; ok {
+			return nil, fmt.Errorf("repeated field %q", name)
+		}
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+// knownKeyField returns true if name is a field of a key string.
+func knownKeyField(name string) bool {
+	for _, field := range keyFields {
+		if name == field {
+			return true
+		}
+	}
+	return false
+}
+
+// parseKeyFields parses the fields of a key string into a Machine.
+func parseKeyFields(fields map[string]string) (*Machine, error) {
+	jsonM := &jsonMachine{
+		Version:  ConfigVersion,
+		Mode:     fields["mode"],
+		Stepping: fields["stepping"],
+	}
+
+	var err error
+	jsonM.Alphabet, err = url.PathUnescape(fields["alpha"])
+	if err != nil {
+		return nil, fmt.Errorf("alpha: %w", err)
+	}
+	alphabet, err := parseAlphabet(jsonM.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := ParseMode(jsonM.Mode)
+	if err != nil {
+		return nil, err
+	}
+
+	if fields["rotors"] == "" {
+		return nil, fmt.Errorf("no rotors given")
+	}
+	wirings, err := splitKeyList(fields["rotors"], -1)
+	if err != nil {
+		return nil, fmt.Errorf("rotors: %w", err)
+	}
+	count := len(wirings)
+
+	positions, err := splitKeyLetters(fields["pos"], count)
+	if err != nil {
+		return nil, fmt.Errorf("pos: %w", err)
+	}
+	rings, err := splitKeyLetters(fields["ring"], count)
+	if err != nil {
+		return nil, fmt.Errorf("ring: %w", err)
+	}
+	steps, err := splitKeyList(fields["steps"], count)
+	if err != nil {
+		return nil, fmt.Errorf("steps: %w", err)
+	}
+	notches, err := splitKeyList(fields["notch"], count)
+	if err != nil {
+		return nil, fmt.Errorf("notch: %w", err)
+	}
+	stationary, err := splitKeyList(fields["stationary"], count)
+	if err != nil {
+		return nil, fmt.Errorf("stationary: %w", err)
+	}
+	if positions == nil {
+		return nil, fmt.Errorf("no positions given")
+	}
+
+	jsonM.Rotors = make([]*jsonRotor, count)
+	for i, wiring := range wirings {
+		rotor := &jsonRotor{
+			Position: positions[i],
+		}
+		jsonM.Rotors[i] = rotor
+
+		if letters := []rune(wiring); len(letters) == alphabet.Size() {
+			rotor.Pathways = make([]string, len(letters))
+			for j, letter := range letters {
+				rotor.Pathways[j] = string(letter)
+			}
+		} else {
+			rotor.Wiring = wiring
+		}
+
+		if rings != nil {
+			rotor.Ring = rings[i]
+		}
+
+		if steps != nil {
+			if rotor.Step, rotor.Cycle, err = parseStepCycle(steps[i]); err != nil {
+				return nil, fmt.Errorf("steps: %w", err)
+			}
+		} else if mode != EnigmaMode {
+			rotor.Step, rotor.Cycle = DefaultStep, alphabet.Size()
+		}
+
+		if notches != nil && notches[i] != "" {
+			for _, notch := range notches[i] {
+				rotor.Notches = append(rotor.Notches, string(notch))
+			}
+		}
+
+		if stationary != nil && stationary[i] != "" {
+			fixed := stationary[i] == "1"
+			if !fixed && stationary[i] != "0" {
+				return nil, fmt.Errorf("stationary: invalid value %q, expected 0 or 1", stationary[i])
+			}
+			rotor.Stationary = &fixed
+		}
+	}
+
+	reflector, err := url.PathUnescape(fields["refl"])
+	if err != nil {
+		return nil, fmt.Errorf("refl: %w", err)
+	}
+	switch letters := []rune(reflector); {
+	case len(letters) == 0:
+		return nil, fmt.Errorf("no reflector given")
+	case len(letters) == alphabet.Size():
+		jsonM.Reflector = &jsonReflector{
+			Connections: make(map[string]string),
+		}
+		for i, letter := range letters {
+			jsonM.Reflector.Connections[intToStr(i, alphabet)] = string(letter)
+		}
+	default:
+		jsonM.Reflector = &jsonReflector{
+			Name: reflector,
+		}
+	}
+
+	var pairs []string
+	for _, pair := range strings.Fields(fields["plug"]) {
+		unescaped, err := url.PathUnescape(pair)
+		if err != nil {
+			return nil, fmt.Errorf("plug: %w", err)
+		}
+		pairs = append(pairs, unescaped)
+	}
+	noPairs := ""
+	jsonM.Plugboard = &jsonPlugboard{
+		Pairs: &noPairs,
+	}
+
+	m, err := parseMachine(jsonM)
+	if err != nil {
+		return nil, err
+	}
+	m.plugboard, err = parsePairList(pairs, alphabet)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseStepCycle parses a step and a cycle written as "step:cycle".
+func parseStepCycle(parse string) (step, cycle int, err error) {
+	colon := strings.Index(parse, ":")
+	if colon < 0 {
+		return 0, 0, fmt.Errorf("invalid value %q, expected step:cycle", parse)
+	}
+	if step, err = strconv.Atoi(parse[:colon]); err != nil {
+		return 0, 0, fmt.Errorf("invalid step %q", parse[:colon])
+	}
+	if cycle, err = strconv.Atoi(parse[colon+1:]); err != nil {
+		return 0, 0, fmt.Errorf("invalid cycle %q", parse[colon+1:])
+	}
+	return step, cycle, nil
+}
+
+// splitKeyList splits a comma separated list and unescapes its elements.
+// An error is returned if count isn't negative and the list doesn't have
+// count elements. nil is returned for an empty value.
+func splitKeyList(value string, count int) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	list := strings.Split(value, ",")
+	if count >= 0 && len(list) != count {
+		return nil, fmt.Errorf("expected %d values, got %d", count, len(list))
+	}
+	for i, element := range list {
+		unescaped, err := url.PathUnescape(element)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = unescaped
+	}
+	return list, nil
+}
+
+// splitKeyLetters unescapes a value and splits it into characters, and
+// returns an error if it doesn't contain count characters. nil is returned
+// for an empty value.
+func splitKeyLetters(value string, count int) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	unescaped, err := url.PathUnescape(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var letters []string
+	for _, letter := range unescaped {
+		letters = append(letters, string(letter))
+	}
+	if len(letters) != count {
+		return nil, fmt.Errorf("expected %d characters, got %d", count, len(letters))
+	}
+	return letters, nil
+}
+
+// escapeKey escapes characters that can't be written as is in a key string,
+// which are separators, spaces, percent signs, and non-graphic characters,
+// using URL percent-encoding.
+func escapeKey(value string) string {
+	var escaped strings.Builder
+	for _, char := range value {
+		if char != '%' && char != ';' && char != ',' && unicode.IsGraphic(char) && !unicode.IsSpace(char) {
+			escaped.WriteRune(char)
+			continue
+		}
+
+		var buffer [utf8.UTFMax]byte
+		for _, b := range buffer[:utf8.EncodeRune(buffer[:], char)] {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
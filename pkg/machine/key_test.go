@@ -0,0 +1,135 @@
+package machine
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestKeyString tests that machines written as key strings are parsed into
+// identical machines.
+func TestKeyString(t *testing.T) {
+	unexported := cmp.AllowUnexported(
+		Machine{},
+		Rotors{},
+		Rotor{},
+		Plugboard{},
+		Reflector{},
+	)
+
+	keystream := GenerateWithSource(5, rand.NewSource(7))
+	keystream.stepper = KeystreamStepper{}
+
+	var machines []*Machine
+	for _, path := range []string{
+		"../../test-data/config-1.json",
+		"../../test-data/config-3.json",
+		"../../test-data/enigma-config-1.json",
+		"../../test-data/enigma-config-2.json",
+	} {
+		m, err := Read(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		machines = append(machines, m)
+	}
+	machines = append(machines,
+		GenerateWithSource(10, rand.NewSource(1)),
+		GenerateWithAlphabet(3, PrintableAlphabet, rand.NewSource(2)),
+		GenerateWithAlphabet(3, mustAlphabet("ab ;,%=:"), rand.NewSource(3)),
+		keystream,
+	)
+
+	for i, m := range machines {
+		key, err := m.KeyString()
+		if err != nil {
+			t.Errorf("test %d: failed to write key: %v", i, err)
+			continue
+		}
+
+		r, err := ParseKey(key)
+		if err != nil {
+			t.Errorf("test %d: failed to parse key %s: %v", i, key, err)
+			continue
+		}
+		if diff := cmp.Diff(*m, *r, unexported); diff != "" {
+			t.Errorf("test %d: mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestParseKey tests parsing handwritten key strings.
+func TestParseKey(t *testing.T) {
+	for i, test := range []struct {
+		key       string
+		message   string
+		encrypted string
+		valid     bool
+	}{
+		{
+			key:       "X1:mode=enigma;rotors=III,II,I;pos=aaa;refl=UKW-B",
+			message:   "AAAAA",
+			encrypted: "bdzgo",
+			valid:     true,
+		},
+		{
+			key:       " X1: refl=B-thin; plug=AT BL DF GJ HM NW OP QY RZ VX; rotors=I,IV,II,Beta; pos=ANJV; ring=VAAA; mode=enigma; ",
+			message:   "NCZWVUSXPNYM",
+			encrypted: "vonvonjlooks",
+			valid:     true,
+		},
+		{
+			key: "X2:mode=enigma;rotors=III,II,I;pos=aaa;refl=UKW-B",
+		},
+		{
+			key: "X1:mode=enigma;rotors=III,II,I;pos=aa;refl=UKW-B",
+		},
+		{
+			key: "X1:mode=enigma;rotors=III,II,I;pos=aaa;refl=UKW-B;plug=ab ac",
+		},
+		{
+			key: "X1:mode=enigma;rotors=III,II,I;pos=aaa;refl=UKW-B;rotors=I",
+		},
+		{
+			key: "X1:mode=enigma;rotors=III,II,I;pos=aaa;refl=UKW-B;wheels=I",
+		},
+		{
+			key: "X1:rotors=III,II,I;pos=aaa;steps=1:26,1:26;refl=UKW-B",
+		},
+		{
+			key: "X1:rotors=III;pos=a;steps=26:1;refl=UKW-B",
+		},
+		{
+			key: "X1:rotors=III;pos=a;steps=52:1;refl=UKW-B",
+		},
+	} {
+		m, err := ParseKey(test.key)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("test %d: valid: %t, want: %t, error: %v", i, valid, test.valid, err)
+		}
+		if err != nil {
+			continue
+		}
+
+		encrypted, err := m.Encrypt(test.message)
+		if err != nil {
+			t.Errorf("test %d: failed to encrypt: %v", i, err)
+		}
+		if encrypted != test.encrypted {
+			t.Errorf("test %d: incorrect encryption, want: %s, got: %s", i, test.encrypted, encrypted)
+		}
+	}
+}
+
+// TestParseKeyStep tests that a step as large as the alphabet is rejected
+// with a validation error.
+func TestParseKeyStep(t *testing.T) {
+	_, err := ParseKey("X1:rotors=III;pos=a;steps=26:1;refl=UKW-B")
+
+	var validation *ValidationError
+	if !errors.As(err, &validation) || validation.Path != "rotors[0].step" {
+		t.Errorf("want validation error of rotors[0].step, got: %v", err)
+	}
+}
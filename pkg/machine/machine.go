@@ -10,10 +10,30 @@ following.
     m := machine.Generate(10)
     encrypted := m.Encrypt("Hello, world!")
 
+Large messages can be encrypted incrementally using Machine.NewWriter and
+Machine.NewReader, which wrap an io.Writer and an io.Reader respectively.
+
+Arbitrary binary data can be encrypted using a ByteMachine, which encrypts
+every byte instead of only letters.
+
 Components
 
 Machine's components can be generated or specified at creation, or read as
-JSON.
+JSON, YAML, or TOML. A whole machine can also be derived from a passphrase using
+FromPassphrase, or written as a single-line key string using
+Machine.KeyString and parsed using ParseKey.
+
+Invalid components and config files are reported as ValidationErrors, a
+list of every invalid field, each a *ValidationError containing the path of
+the field, e.g. "rotors[3].pathways[7]", and its invalid and expected values.
+
+Alphabet
+
+A machine encrypts characters of its alphabet, which defaults to the english
+alphabet (LatinAlphabet). Other alphabets can be created using NewAlphabet,
+and must contain an even number of characters. All of machine's components
+must have the same size as its alphabet. The examples below use the english
+alphabet.
 
 Rotors
 
@@ -22,10 +42,11 @@ of the given rotor array. Rotor's fields are pathways, position, step, and
 cycle.
 
 Pathways are the electric connections between characters. They are represented
-using a map-like 26 element array where an index and a character represent a
-map pair. Key and value pairs are translated into their position in the english
-alphabet. For example, if pathways[0]=2, then a is mapped to c. Arrays are chosen
-over maps for pathways because ordering matters.
+using a map-like array, with an element for each character of the alphabet,
+where an index and a character represent a map pair. Key and value pairs are
+translated into their position in the alphabet. For example, if
+pathways[0]=2, then a is mapped to c. Arrays are chosen over maps for
+pathways because ordering matters.
 
 Position is the current position of the rotor, which must be reachable from
 the starting position 0 or "a".
@@ -37,15 +58,36 @@ will change to "d". The default step is 1.
 Cycle is the number of steps needed to complete a full cycle, after which the
 following rotor is shifted. For example, if a rotor with cycle=13, then it
 needs to complete 13 steps for the next rotor to move one step. The default
-cycle is 26.
+cycle is the size of the alphabet.
 
 To avoid position collisions and guarantee that any of the rotor's settings can
-be reached using only one sequence of steps, (step*cycle)%26 must equal zero.
+be reached using only one sequence of steps, (size%(step*cycle)) must equal
+zero, where size is the size of the alphabet.
 Combinations that don't satisfy this relation are considered invalid.
 
+Stepping
+
+Rotors are moved by a machine's Stepper, which can be set using
+Machine.SetStepper. OdometerStepper is the default, and moves rotors according
+to their steps and cycles. EnigmaStepper and TypexStepper move rotors using
+notches, and KeystreamStepper moves rotors irregularly. Custom steppers can
+move rotors using Rotor.TakeStep.
+
+Enigma Mode
+
+A machine created using NewEnigma models the historical Enigma I, M3, and
+M4 machines, so messages encrypted using them can be decrypted. An Enigma
+machine has three rotors created using NewEnigmaRotor, ordered from the
+rightmost (fast) rotor to the leftmost. Each rotor has a ring setting, and
+one or two turnover notches. Rotors move before each letter is encrypted,
+a rotor at a notch moves the rotor to its left, and the middle rotor
+double-steps. An M4 has a fourth, stationary Greek wheel, created using
+NewStationaryRotor, and a thin reflector. Historical wirings can be created
+by name using NewHistoricalRotor and NewHistoricalReflector.
+
 Reflector
 
-Reflector is connections map, which must contain all characters in the english
+Reflector is connections map, which must contain all characters in the
 alphabet, and must be symmetric. Symmetry means that if "a" is connected to "b",
 then "b" must also be connected to "a".
 
@@ -57,29 +99,40 @@ unconnected/unplugged, connect it to itself.
 package machine
 
 import (
-	"fmt"
 	"math/rand"
+	"strconv"
 	"time"
 )
 
-const alphabetSize = 26
-
 // Machine represents a xenigma encryption machine. Machine's components are
-// electric pathways, reflector, plugboard, and rotors.
+// electric pathways, reflector, plugboard, and rotors. All components must
+// have the same size as the machine's alphabet.
 type Machine struct {
+	mode      Mode
+	stepper   Stepper
+	alphabet  *Alphabet
 	rotors    *Rotors
 	plugboard *Plugboard
 	reflector *Reflector
+	opts      EncryptOptions
 }
 
-// New creates and returns a new, initialized Machine, and an error if any of
-// the given fields is invalid.
+// New creates and returns a new, initialized Machine that uses LatinAlphabet,
+// and an error if any of the given fields is invalid.
 func New(rotors *Rotors, plugboard *Plugboard, reflector *Reflector) (*Machine, error) {
-	if err := verifyMachine(rotors, plugboard, reflector); err != nil {
+	return NewWithAlphabet(LatinAlphabet, rotors, plugboard, reflector)
+}
+
+// NewWithAlphabet creates and returns a new, initialized Machine that uses
+// the given alphabet, and an error if any of the given fields is invalid or
+// doesn't match the alphabet's size.
+func NewWithAlphabet(alphabet *Alphabet, rotors *Rotors, plugboard *Plugboard, reflector *Reflector) (*Machine, error) {
+	if err := verifyMachine(XenigmaMode, OdometerStepper{}, alphabet, rotors, plugboard, reflector); err != nil {
 		return nil, err
 	}
 
 	return &Machine{
+		alphabet:  alphabet,
 		rotors:    rotors,
 		plugboard: plugboard,
 		reflector: reflector,
@@ -87,47 +140,215 @@ func New(rotors *Rotors, plugboard *Plugboard, reflector *Reflector) (*Machine,
 }
 
 // Generate generates a machine with the specified number of rotors containing
-// randomly generated component configurations.
+// randomly generated component configurations. Generate uses its own source
+// seeded with the current time, and doesn't affect the global source of
+// math/rand.
 func Generate(numberOfRotors int) *Machine {
-	rand.Seed(time.Now().UnixNano())
+	return GenerateWithSource(numberOfRotors, rand.NewSource(time.Now().UnixNano()))
+}
+
+// GenerateWithSource generates a machine with the specified number of rotors
+// using src as the source of randomness. Machines generated using sources
+// with the same seed are identical.
+func GenerateWithSource(numberOfRotors int, src rand.Source) *Machine {
+	return GenerateWithAlphabet(numberOfRotors, LatinAlphabet, src)
+}
+
+// GenerateWithAlphabet generates a machine with the specified number of
+// rotors that uses the given alphabet, using src as the source of
+// randomness.
+func GenerateWithAlphabet(numberOfRotors int, alphabet *Alphabet, src rand.Source) *Machine {
+	return generate(numberOfRotors, alphabet, rand.New(src))
+}
+
+// GenerateSecure generates a machine with the specified number of rotors
+// using crypto/rand as the source of randomness. GenerateSecure should be
+// preferred over Generate when generated machines are used as keys.
+func GenerateSecure(numberOfRotors int) *Machine {
+	return GenerateWithSource(numberOfRotors, cryptoSource{})
+}
+
+// generate generates a machine with the specified number of rotors and
+// alphabet using the given random number generator.
+func generate(numberOfRotors int, alphabet *Alphabet, rng *rand.Rand) *Machine {
+	size := alphabet.Size()
 	return &Machine{
-		plugboard: GeneratePlugboard(),
-		reflector: GenerateReflector(),
-		rotors:    GenerateRotors(numberOfRotors),
+		alphabet:  alphabet,
+		plugboard: generatePlugboard(size, rng),
+		reflector: generateReflector(size, rng),
+		rotors:    generateRotors(numberOfRotors, size, rng),
 	}
 }
 
 // Verify verifies that all components of the machine are initialized
 // correctly, and returns an error if not.
 func (m *Machine) Verify() error {
-	return verifyMachine(m.rotors, m.plugboard, m.reflector)
+	return verifyMachine(m.mode, m.Stepper(), m.alphabet, m.rotors, m.plugboard, m.reflector)
 }
 
-func verifyMachine(rotors *Rotors, plugboard *Plugboard, reflector *Reflector) error {
-	if rotors == nil {
-		return fmt.Errorf("no rotors given")
+func verifyMachine(mode Mode, stepper Stepper, alphabet *Alphabet, rotors *Rotors, plugboard *Plugboard, reflector *Reflector) error {
+	var errs ValidationErrors
+	if alphabet == nil {
+		errs = append(errs, &ValidationError{Path: "alphabet", Reason: "no alphabet given"})
+	} else {
+		errs.add("alphabet", alphabet.Verify())
 	}
-	if err := rotors.Verify(); err != nil {
-		return err
+
+	if rotors == nil {
+		errs = append(errs, &ValidationError{Path: "rotors", Reason: "no rotors given"})
+	} else if err := rotors.Verify(); err != nil {
+		errs.add("", err)
+	} else {
+		if alphabet != nil && rotors.size() != alphabet.Size() {
+			errs = append(errs, sizeMismatch("rotors", rotors.size(), alphabet.Size()))
+		}
+		errs.add("", verifyMode(mode, stepper, rotors))
 	}
 
 	if reflector == nil {
-		return fmt.Errorf("no reflector given")
-	}
-	if err := reflector.Verify(); err != nil {
-		return err
+		errs = append(errs, &ValidationError{Path: "reflector", Reason: "no reflector given"})
+	} else if err := reflector.Verify(); err != nil {
+		errs.add("reflector", err)
+	} else if alphabet != nil && reflector.Size() != alphabet.Size() {
+		errs = append(errs, sizeMismatch("reflector", reflector.Size(), alphabet.Size()))
 	}
 
 	if plugboard == nil {
-		return fmt.Errorf("no plugboard given")
+		errs = append(errs, &ValidationError{Path: "plugboard", Reason: "no plugboard given"})
+	} else if err := plugboard.Verify(); err != nil {
+		errs.add("plugboard", err)
+	} else if alphabet != nil && plugboard.Size() != alphabet.Size() {
+		errs = append(errs, sizeMismatch("plugboard", plugboard.Size(), alphabet.Size()))
+	}
+
+	return errs.err()
+}
+
+// sizeMismatch returns an error of a component whose size doesn't match the
+// size of the alphabet.
+func sizeMismatch(path string, size, alphabetSize int) *ValidationError {
+	return &ValidationError{
+		Path:   path,
+		Reason: "size doesn't match alphabet size",
+		Got:    strconv.Itoa(size),
+		Want:   strconv.Itoa(alphabetSize),
+	}
+}
+
+// Clone returns a deep copy of the machine. The copy is independent of the
+// original, encrypting using one doesn't affect the other.
+func (m *Machine) Clone() *Machine {
+	clone := &Machine{
+		mode:     m.mode,
+		stepper:  m.stepper,
+		alphabet: m.alphabet,
+		opts:     m.opts,
 	}
-	if err := plugboard.Verify(); err != nil {
+	if m.rotors != nil {
+		clone.rotors = m.rotors.clone()
+	}
+	if m.plugboard != nil {
+		clone.plugboard = m.plugboard.clone()
+	}
+	if m.reflector != nil {
+		clone.reflector = m.reflector.clone()
+	}
+	return clone
+}
+
+// Reset returns machine's rotors to their positions at creation, or at the
+// time the machine was read. A reset machine can decrypt messages encrypted
+// before resetting.
+func (m *Machine) Reset() {
+	if m.rotors != nil {
+		m.rotors.Reset()
+	}
+}
+
+// Advance moves machine's rotors forward as if n letters were encrypted.
+// With an OdometerStepper Advance is computed arithmetically in O(number of
+// rotors), so it can be used to skip over a part of a message without
+// encrypting it. With other steppers rotors are stepped n times. Advance
+// does nothing on a machine without rotors.
+func (m *Machine) Advance(n uint64) {
+	if m.rotors == nil {
+		return
+	}
+
+	if m.advancesArithmetically() {
+		m.rotors.advance(n)
+		return
+	}
+
+	stepper := m.Stepper()
+	for ; n > 0; n-- {
+		stepper.Step(m.rotors.rotors)
+	}
+}
+
+// advancesArithmetically returns true if Advance computes the state of
+// machine's rotors arithmetically, rather than stepping them n times.
+func (m *Machine) advancesArithmetically() bool {
+	_, ok := m.Stepper().(OdometerStepper)
+	return ok
+}
+
+// SeekTo resets the machine, then moves its rotors to the state reached
+// after encrypting n letters. Non-alphabetical characters don't move rotors
+// and so aren't counted in n. SeekTo does nothing on a machine without
+// rotors.
+func (m *Machine) SeekTo(n uint64) {
+	m.Reset()
+	m.Advance(n)
+}
+
+// Mode returns machine's mode.
+func (m *Machine) Mode() Mode {
+	return m.mode
+}
+
+// SetStepper sets the stepper used to move machine's rotors, and returns an
+// error if machine's rotors can't be moved by it, in which case the stepper
+// is not changed. A nil stepper restores the default stepper of machine's
+// mode.
+func (m *Machine) SetStepper(stepper Stepper) error {
+	if stepper == nil {
+		stepper = defaultStepper(m.mode)
+	}
+	if m.rotors == nil {
+		return &ValidationError{Path: "rotors", Reason: "no rotors given"}
+	}
+	if err := verifyMode(m.mode, stepper, m.rotors); err != nil {
 		return err
 	}
 
+	m.stepper = stepper
 	return nil
 }
 
+// Stepper returns the stepper used to move machine's rotors.
+func (m *Machine) Stepper() Stepper {
+	if m.stepper == nil {
+		return defaultStepper(m.mode)
+	}
+	return m.stepper
+}
+
+// SetOptions sets the options used to encrypt messages.
+func (m *Machine) SetOptions(opts EncryptOptions) {
+	m.opts = opts
+}
+
+// Options returns the options used to encrypt messages.
+func (m *Machine) Options() EncryptOptions {
+	return m.opts
+}
+
+// Alphabet returns machine's alphabet.
+func (m *Machine) Alphabet() *Alphabet {
+	return m.alphabet
+}
+
 // Rotors returns machine's rotors.
 func (m *Machine) Rotors() *Rotors {
 	return m.rotors
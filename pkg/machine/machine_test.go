@@ -0,0 +1,158 @@
+package machine
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestGenerateWithSource tests that machines generated using sources with
+// the same seed are identical and valid.
+func TestGenerateWithSource(t *testing.T) {
+	unexported := cmp.AllowUnexported(
+		Machine{},
+		Rotors{},
+		Rotor{},
+		Plugboard{},
+		Reflector{},
+	)
+
+	for i, seed := range []int64{0, 1, 42, 1 << 40} {
+		m := GenerateWithSource(5, rand.NewSource(seed))
+		if err := m.Verify(); err != nil {
+			t.Errorf("test %d: generated invalid machine: %v", i, err)
+		}
+
+		r := GenerateWithSource(5, rand.NewSource(seed))
+		if diff := cmp.Diff(*m, *r, unexported); diff != "" {
+			t.Errorf("test %d: mismatch (-want +got):\n%s", i, diff)
+		}
+
+		o := GenerateWithSource(5, rand.NewSource(seed+1))
+		if cmp.Equal(*m, *o, unexported) {
+			t.Errorf("test %d: different seeds generated identical machines", i)
+		}
+	}
+}
+
+// TestGenerateSecure tests that machines generated using crypto/rand are
+// valid and differ from each other.
+func TestGenerateSecure(t *testing.T) {
+	unexported := cmp.AllowUnexported(
+		Machine{},
+		Rotors{},
+		Rotor{},
+		Plugboard{},
+		Reflector{},
+	)
+
+	m := GenerateSecure(5)
+	if err := m.Verify(); err != nil {
+		t.Errorf("generated invalid machine: %v", err)
+	}
+
+	r := GenerateSecure(5)
+	if err := r.Verify(); err != nil {
+		t.Errorf("generated invalid machine: %v", err)
+	}
+
+	if cmp.Equal(*m, *r, unexported) {
+		t.Errorf("generated identical machines")
+	}
+}
+
+// TestClone tests that a cloned machine is identical to, and independent
+// of, the original.
+func TestClone(t *testing.T) {
+	m, err := Read("../../test-data/config-1.json")
+	if err != nil {
+		t.Fatalf("failed to read machine: %v", err)
+	}
+
+	clone := m.Clone()
+	if diff := cmp.Diff(*m, *clone, cmp.AllowUnexported(Machine{}, Rotors{}, Rotor{}, Plugboard{}, Reflector{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	original, err := m.Encrypt("Hello, world!")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	cloned, err := clone.Encrypt("Hello, world!")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	if original != cloned {
+		t.Errorf("different encryption, original: %s, clone: %s", original, cloned)
+	}
+
+	clone.plugboard.connections[0] = 0
+	if m.plugboard.connections[0] == 0 {
+		t.Errorf("modifying clone's plugboard modified original")
+	}
+}
+
+// TestReset tests that a reset machine decrypts a message encrypted before
+// resetting.
+func TestReset(t *testing.T) {
+	m, err := Read("../../test-data/config-1.json")
+	if err != nil {
+		t.Fatalf("failed to read machine: %v", err)
+	}
+	setting := m.Rotors().Setting()
+
+	message := "Hello, world!"
+	encrypted, err := m.Encrypt(message)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	m.Reset()
+	if diff := cmp.Diff(setting, m.Rotors().Setting()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	decrypted, err := m.Encrypt(encrypted)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if decrypted != "hello, world!" {
+		t.Errorf("failed to decrypt: want %s, got %s", message, decrypted)
+	}
+
+	// Resetting a machine without rotors does nothing.
+	new(Machine).Reset()
+}
+
+// TestSeekTo tests that seeking to the middle of a message produces the
+// same encryption of the rest of the message.
+func TestSeekTo(t *testing.T) {
+	m, err := Read("../../test-data/config-3.json")
+	if err != nil {
+		t.Fatalf("failed to read machine: %v", err)
+	}
+
+	prefix, suffix := "The first half of a message,", " and the second half."
+	encrypted, err := m.Encrypt(prefix + suffix)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	m.SeekTo(22) // Number of letters in prefix.
+	got, err := m.Encrypt(suffix)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	if want := encrypted[len(prefix):]; got != want {
+		t.Errorf("incorrect encryption, want: %s, got: %s", want, got)
+	}
+	// Advancing and seeking on a machine without rotors does nothing.
+	for _, mode := range []Mode{XenigmaMode, EnigmaMode} {
+		m := &Machine{mode: mode}
+		m.Advance(22)
+		m.SeekTo(22)
+	}
+}
@@ -5,8 +5,8 @@ package machine
 // mappings of one character to another.
 
 import (
-	"fmt"
 	"math/rand"
+	"strconv"
 )
 
 // Plugboard is a set of connections that maps different characters to each
@@ -45,45 +45,80 @@ func NewReflector(connections map[int]int) (*Reflector, error) {
 	}, nil
 }
 
-// GeneratePlugboard generates a plugboard with random configurations and
-// returns a pointer to it.
+// GeneratePlugboard generates a plugboard of LatinAlphabet with random
+// configurations and returns a pointer to it.
 func GeneratePlugboard() *Plugboard {
+	return generatePlugboard(LatinAlphabet.Size(), newRand())
+}
+
+// GenerateReflector generates a reflector of LatinAlphabet with random
+// configurations and returns a pointer to it.
+func GenerateReflector() *Reflector {
+	return generateReflector(LatinAlphabet.Size(), newRand())
+}
+
+// generatePlugboard generates a plugboard of the given size using the given
+// random number generator.
+func generatePlugboard(size int, rng *rand.Rand) *Plugboard {
 	return &Plugboard{
-		connections: generateConnections(),
+		connections: generateConnections(size, rng),
 	}
 }
 
-// GenerateReflector generates a reflector with random configurations and
-// returns a pointer to it.
-func GenerateReflector() *Reflector {
+// generateReflector generates a reflector of the given size using the given
+// random number generator.
+func generateReflector(size int, rng *rand.Rand) *Reflector {
 	return &Reflector{
-		connections: generateConnections(),
+		connections: generateConnections(size, rng),
 	}
 }
 
 // generateConnections generates a random map of symmetric connections populated
-// with elements 0 through n-1. Symmetric means that if slice[n] = m, then
-// slice[m] = n.
-func generateConnections() map[int]int {
-	var ordered [alphabetSize]int
-	for i := 0; i < alphabetSize; i++ {
+// with elements 0 through n-1, where n is an even size. Symmetric means that if
+// slice[n] = m, then slice[m] = n. Randomness is drawn from rng.
+func generateConnections(n int, rng *rand.Rand) map[int]int {
+	ordered := make([]int, n)
+	for i := 0; i < n; i++ {
 		ordered[i] = i
 	}
 
-	rand.Shuffle(
-		alphabetSize,
+	rng.Shuffle(
+		n,
 		func(i, j int) {
 			ordered[i], ordered[j] = ordered[j], ordered[i]
 		},
 	)
 
 	connections := make(map[int]int)
-	for i := 0; i < alphabetSize/2; i++ {
-		connections[ordered[i]], connections[ordered[i+13]] = ordered[i+13], ordered[i]
+	for i := 0; i < n/2; i++ {
+		connections[ordered[i]], connections[ordered[i+n/2]] = ordered[i+n/2], ordered[i]
 	}
 	return connections
 }
 
+// clone returns a deep copy of the plugboard.
+func (p *Plugboard) clone() *Plugboard {
+	return &Plugboard{
+		connections: cloneConnections(p.connections),
+	}
+}
+
+// clone returns a deep copy of the reflector.
+func (r *Reflector) clone() *Reflector {
+	return &Reflector{
+		connections: cloneConnections(r.connections),
+	}
+}
+
+// cloneConnections returns a copy of the given connections map.
+func cloneConnections(connections map[int]int) map[int]int {
+	clone := make(map[int]int, len(connections))
+	for k, v := range connections {
+		clone[k] = v
+	}
+	return clone
+}
+
 // Connections returns plugboard's connections map.
 func (p *Plugboard) Connections() map[int]int {
 	return p.connections
@@ -95,15 +130,16 @@ func (r *Reflector) Connections() map[int]int {
 }
 
 // PlugIn returns the int mapped to char based on plugboard's
-// connections. Should be used when a character is entered.
-func (p *Plugboard) PlugIn(char byte) int {
-	return p.connections[int(char-'a')]
+// connections. Should be used when a character is entered. Characters are
+// represented by their position in the machine's alphabet.
+func (p *Plugboard) PlugIn(char int) int {
+	return p.connections[char]
 }
 
-// PlugOut returns the byte mapped to char based on plugboard's
+// PlugOut returns the int mapped to char based on plugboard's
 // connections. Should be used when a character is returned.
-func (p *Plugboard) PlugOut(char int) byte {
-	return byte(p.connections[char] + 'a')
+func (p *Plugboard) PlugOut(char int) int {
+	return p.connections[char]
 }
 
 // Reflect returns the reflection of the given character using reflector's
@@ -122,16 +158,21 @@ func (r *Reflector) Verify() error {
 	return verifyConnections(r.connections)
 }
 
-// verifyConnections verifies that given connections are valid, and returns an
-// error if not.
-func verifyConnections(connections map[int]int) error {
-	if !zeroToN(connections, alphabetSize) {
-		return fmt.Errorf("connections are invalid")
-	}
+// Size returns the number of plugboard's connections, which is the size of
+// the alphabet it's used with.
+func (p *Plugboard) Size() int {
+	return len(p.connections)
+}
 
-	if !isSymmetric(connections) {
-		return fmt.Errorf("connections are not symmetric")
-	}
+// Size returns the number of reflector's connections, which is the size of
+// the alphabet it's used with.
+func (r *Reflector) Size() int {
+	return len(r.connections)
+}
 
-	return nil
+// verifyConnections verifies that given connections are valid, and returns an
+// error if not. Connections must map elements 0 through n-1, where n is the
+// number of connections.
+func verifyConnections(connections map[int]int) error {
+	return verifyConnectionMap(connections, strconv.Itoa).err()
 }
@@ -0,0 +1,74 @@
+package machine
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// ConfigVersion is the version of the config schema written by Write. Configs
+// written before the schema was versioned have no version field, and are
+// version 0.
+const ConfigVersion = 1
+
+// migrations upgrade a jsonMachine from one version of the config schema to
+// the next, migrations[i] upgrades version i to version i+1. A change to the
+// schema that would make older configs invalid must increment ConfigVersion
+// and add a migration, keeping any removed fields in jsonMachine so that
+// older configs can still be read.
+var migrations = []func(jsonM *jsonMachine) error{
+	// Version 1 adds the version field, and is otherwise the same as
+	// unversioned configs.
+	func(jsonM *jsonMachine) error { return nil },
+}
+
+// migrate upgrades a jsonMachine to ConfigVersion, and returns an error if
+// its version is unknown.
+func migrate(jsonM *jsonMachine) error {
+	if jsonM.Version < 0 || jsonM.Version > ConfigVersion {
+		return fmt.Errorf("unsupported config version %d, latest supported version is %d", jsonM.Version, ConfigVersion)
+	}
+
+	for jsonM.Version < ConfigVersion {
+		if err := migrations[jsonM.Version](jsonM); err != nil {
+			return fmt.Errorf("failed to migrate config from version %d: %w", jsonM.Version, err)
+		}
+		jsonM.Version++
+	}
+	return nil
+}
+
+// Migrate upgrades the config file at path to ConfigVersion, rewriting it in
+// place in its current format, and returns the version the file had before
+// migrating. Files that are already at ConfigVersion aren't rewritten.
+// Rewritten files are written the same way as Write writes them, so comments
+// and formatting aren't kept.
+func Migrate(path string) (int, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	format, ok := formatOf(path)
+	if !ok {
+		format = DetectFormat(contents)
+	}
+
+	jsonM, err := unmarshalFormat(contents, format)
+	if err != nil {
+		return 0, fmt.Errorf("could not unmarshal %s: %w", path, err)
+	}
+
+	version := jsonM.Version
+	if version == ConfigVersion {
+		return version, nil
+	}
+
+	m, err := parseMachine(jsonM)
+	if err != nil {
+		return version, fmt.Errorf("could not migrate %s: %w", path, err)
+	}
+	if err := writeFormat(m, path, format); err != nil {
+		return version, err
+	}
+	return version, nil
+}
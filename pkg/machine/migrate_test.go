@@ -0,0 +1,117 @@
+package machine
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestConfigVersions tests that configs of every version are read as the
+// same machine.
+func TestConfigVersions(t *testing.T) {
+	unexported := cmp.AllowUnexported(
+		Machine{},
+		Rotors{},
+		Rotor{},
+		Plugboard{},
+		Reflector{},
+	)
+
+	want, err := Read("../../test-data/config-v1.json")
+	if err != nil {
+		t.Fatalf("failed to read latest version: %v", err)
+	}
+
+	for i, path := range []string{
+		"../../test-data/config-v0.json",
+		"../../test-data/config-v1.yaml",
+	} {
+		got, err := Read(path)
+		if err != nil {
+			t.Errorf("test %d: failed to read %s: %v", i, path, err)
+			continue
+		}
+		if diff := cmp.Diff(*want, *got, unexported); diff != "" {
+			t.Errorf("test %d: %s mismatch (-want +got):\n%s", i, path, diff)
+		}
+	}
+
+	_, err = Read("../../test-data/wrong-config-version.json")
+	if err == nil || !strings.Contains(err.Error(), "unsupported config version 2") {
+		t.Errorf("incorrect error for unsupported version: %v", err)
+	}
+}
+
+// TestMigrate tests migrating config files in place.
+func TestMigrate(t *testing.T) {
+	err := os.MkdirAll("../../test-data/generate", os.ModePerm)
+	if err != nil {
+		t.Fatal("failed to create test-data/generate")
+	}
+
+	for i, test := range []struct {
+		fixture string
+		path    string
+		version int
+		format  Format
+	}{
+		{
+			fixture: "../../test-data/config-v0.json",
+			path:    "../../test-data/generate/migrate.json",
+			version: 0,
+			format:  JSON,
+		},
+		{
+			fixture: "../../test-data/config-v0.json",
+			path:    "../../test-data/generate/migrate.conf",
+			version: 0,
+			format:  JSON,
+		},
+		{
+			fixture: "../../test-data/config-v1.yaml",
+			path:    "../../test-data/generate/migrate.yaml",
+			version: 1,
+			format:  YAML,
+		},
+	} {
+		contents, err := ioutil.ReadFile(test.fixture)
+		if err != nil {
+			t.Fatalf("test %d: failed to read fixture: %v", i, err)
+		}
+		if err := ioutil.WriteFile(test.path, contents, 0664); err != nil {
+			t.Fatalf("test %d: failed to write: %v", i, err)
+		}
+
+		version, err := Migrate(test.path)
+		if err != nil {
+			t.Errorf("test %d: failed to migrate: %v", i, err)
+			continue
+		}
+		if version != test.version {
+			t.Errorf("test %d: incorrect version, want: %d, got: %d", i, test.version, version)
+		}
+
+		migrated, err := ioutil.ReadFile(test.path)
+		if err != nil {
+			t.Errorf("test %d: failed to read migrated file: %v", i, err)
+			continue
+		}
+		if got := DetectFormat(migrated); got != test.format {
+			t.Errorf("test %d: incorrect format, want: %v, got: %v", i, test.format, got)
+		}
+		jsonM, err := unmarshalFormat(migrated, test.format)
+		if err != nil {
+			t.Errorf("test %d: failed to unmarshal migrated file: %v", i, err)
+			continue
+		}
+		if jsonM.Version != ConfigVersion {
+			t.Errorf("test %d: incorrect migrated version, want: %d, got: %d", i, ConfigVersion, jsonM.Version)
+		}
+		if test.version == ConfigVersion && string(migrated) != string(contents) {
+			t.Errorf("test %d: file at latest version was rewritten", i)
+		}
+	}
+}
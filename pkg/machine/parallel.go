@@ -0,0 +1,80 @@
+package machine
+
+import (
+	"bytes"
+	"sync"
+	"unicode/utf8"
+)
+
+// chunk is a part of a message encrypted by one worker.
+type chunk struct {
+	start   int    // Start of chunk in the message.
+	end     int    // End of chunk in the message.
+	letters uint64 // Number of letters preceding the chunk.
+}
+
+// EncryptParallel encrypts UTF-8 encoded data using the given number of
+// workers, and returns the encrypted bytes and an error if the machine's
+// fields are invalid. The result is identical to that of Encrypt, and the
+// machine is left in the same state as if Encrypt was used.
+//
+// Data is split into chunks, each encrypted by a clone of the machine that
+// is advanced to the chunk's start. Only machines using an OdometerStepper,
+// the default stepper of XenigmaMode, are advanced arithmetically, so data is
+// only encrypted in parallel by such machines. Machines using other
+// steppers, e.g. machines in EnigmaMode, encrypt data sequentially, since
+// advancing them to a chunk's start takes as long as encrypting it.
+func (m *Machine) EncryptParallel(data []byte, workers int) ([]byte, error) {
+	if err := m.Verify(); err != nil {
+		return nil, err
+	}
+	if workers <= 1 || !m.advancesArithmetically() {
+		return m.encryptBytes(data, reverseConnections(m), m.opts), nil
+	}
+
+	chunks, letters := splitChunks(data, workers, m.alphabet, m.opts)
+	reversed := reverseConnections(m)
+	encrypted := make([][]byte, len(chunks))
+
+	var wg sync.WaitGroup
+	for i, c := range chunks {
+		wg.Add(1)
+		go func(i int, c chunk) {
+			defer wg.Done()
+
+			clone := m.Clone()
+			clone.Advance(c.letters)
+			encrypted[i] = clone.encryptBytes(data[c.start:c.end], reversed, m.opts)
+		}(i, c)
+	}
+	wg.Wait()
+
+	m.Advance(letters)
+	return bytes.Join(encrypted, nil), nil
+}
+
+// splitChunks splits data into at most n chunks of roughly equal sizes at
+// rune boundaries. The total number of letters encrypted using the given
+// alphabet and options is also returned.
+func splitChunks(data []byte, n int, alphabet *Alphabet, opts EncryptOptions) (chunks []chunk, letters uint64) {
+	size := (len(data) + n - 1) / n
+	current := chunk{}
+	for i := 0; i < len(data); {
+		if i-current.start >= size {
+			current.end = i
+			chunks = append(chunks, current)
+			current = chunk{
+				start:   i,
+				letters: letters,
+			}
+		}
+
+		char, width := utf8.DecodeRune(data[i:])
+		letters += alphabet.count(char, opts)
+		i += width
+	}
+
+	current.end = len(data)
+	chunks = append(chunks, current)
+	return chunks, letters
+}
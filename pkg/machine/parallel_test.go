@@ -0,0 +1,129 @@
+package machine
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestEncryptParallel compares parallel encryption using different numbers
+// of workers with sequential encryption.
+func TestEncryptParallel(t *testing.T) {
+	license, err := ioutil.ReadFile("../../LICENSE")
+	if err != nil {
+		t.Fatalf("failed to read LICENSE: %v", err)
+	}
+
+	opts := EncryptOptions{
+		PreserveCase:  true,
+		Transliterate: true,
+	}
+	for i, message := range append(streamMessages, string(license), "") {
+		for _, workers := range []int{0, 1, 2, 3, 8, 100} {
+			m := GenerateWithSource(10, rand.NewSource(int64(i)))
+			m.SetOptions(opts)
+			sequential := m.Clone()
+
+			want, err := sequential.Encrypt(message)
+			if err != nil {
+				t.Fatalf("failed to encrypt: %v", err)
+			}
+
+			got, err := m.EncryptParallel([]byte(message), workers)
+			if err != nil {
+				t.Fatalf("test %d, workers %d: failed to encrypt: %v", i, workers, err)
+			}
+
+			if string(got) != want {
+				t.Errorf("test %d, workers %d: incorrect encryption", i, workers)
+			}
+			if diff := cmp.Diff(sequential.State(), m.State()); diff != "" {
+				t.Errorf("test %d, workers %d: state mismatch (-want +got):\n%s", i, workers, diff)
+			}
+		}
+	}
+}
+
+// countingStepper is an OdometerStepper that counts its steps.
+type countingStepper struct {
+	steps *int
+}
+
+func (s countingStepper) Step(rotors []*Rotor) {
+	*s.steps++
+	OdometerStepper{}.Step(rotors)
+}
+
+// TestEncryptParallelSteppers tests that machines using steppers other than
+// OdometerStepper are encrypted sequentially, stepping once per letter.
+func TestEncryptParallelSteppers(t *testing.T) {
+	license, err := ioutil.ReadFile("../../LICENSE")
+	if err != nil {
+		t.Fatalf("failed to read LICENSE: %v", err)
+	}
+
+	for i, test := range []struct {
+		path    string
+		stepper Stepper
+	}{
+		{"../../test-data/enigma-config-1.json", nil},
+		{"../../test-data/enigma-config-2.json", nil},
+		{"../../test-data/enigma-config-1.json", TypexStepper{}},
+		{"../../test-data/config-3.json", KeystreamStepper{}},
+		{"../../test-data/config-3.json", countingStepper{new(int)}},
+	} {
+		m, err := Read(test.path)
+		if err != nil {
+			t.Fatalf("test %d: failed to read machine: %v", i, err)
+		}
+		if test.stepper != nil {
+			if err := m.SetStepper(test.stepper); err != nil {
+				t.Fatalf("test %d: failed to set stepper: %v", i, err)
+			}
+		}
+		sequential := m.Clone()
+
+		want, err := sequential.Encrypt(string(license))
+		if err != nil {
+			t.Fatalf("test %d: failed to encrypt: %v", i, err)
+		}
+
+		var steps int
+		if counting, ok := test.stepper.(countingStepper); ok {
+			steps = *counting.steps
+			*counting.steps = 0
+		}
+
+		got, err := m.EncryptParallel(license, 8)
+		if err != nil {
+			t.Fatalf("test %d: failed to encrypt: %v", i, err)
+		}
+
+		if string(got) != want {
+			t.Errorf("test %d: incorrect encryption", i)
+		}
+		if diff := cmp.Diff(sequential.State(), m.State()); diff != "" {
+			t.Errorf("test %d: state mismatch (-want +got):\n%s", i, diff)
+		}
+		if counting, ok := test.stepper.(countingStepper); ok && *counting.steps != steps {
+			t.Errorf("test %d: incorrect number of steps, want: %d, got: %d", i, steps, *counting.steps)
+		}
+	}
+}
+
+// BenchmarkEncryptParallelLICENSE benchmarks parallel encryption of LICENSE
+// using a 1000-rotor machine.
+func BenchmarkEncryptParallelLICENSE(b *testing.B) {
+	m := Generate(1000)
+	contents, err := ioutil.ReadFile("../../LICENSE")
+	if err != nil {
+		b.Fatalf("failed to read contents of LICENSE: %s", err.Error())
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.EncryptParallel(contents, 8)
+	}
+}
@@ -0,0 +1,123 @@
+package machine
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// keySize is the size, in bytes, of the key derived from a passphrase.
+const keySize = 32
+
+// KDFParams are the cost parameters of scrypt, the key derivation function
+// used to derive a machine from a passphrase. N is the CPU/memory cost and
+// must be a power of two greater than 1, R is the block size, and P is the
+// parallelization factor.
+type KDFParams struct {
+	N int
+	R int
+	P int
+}
+
+// DefaultKDFParams are the recommended scrypt parameters for interactive
+// use.
+var DefaultKDFParams = KDFParams{
+	N: 1 << 15,
+	R: 8,
+	P: 1,
+}
+
+// FromPassphrase derives a machine with the specified number of rotors from
+// a passphrase and a salt. The same passphrase, salt, number of rotors, and
+// params always produce the same machine, so a machine can be shared by
+// sharing these values instead of a config file.
+//
+// The passphrase is stretched using scrypt, and the derived key is expanded
+// into rotor pathways, positions, steps and cycles, a plugboard, and a
+// reflector. An error is returned if params are invalid.
+func FromPassphrase(pass, salt string, rotors int, params KDFParams) (*Machine, error) {
+	if rotors <= 0 {
+		return nil, fmt.Errorf("invalid number of rotors: %d", rotors)
+	}
+
+	key, err := scrypt.Key([]byte(pass), []byte(salt), params.N, params.R, params.P, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	rng := rand.New(newKeySource(key))
+	m := generate(rotors, LatinAlphabet, rng)
+
+	size := LatinAlphabet.Size()
+	pairs := stepCycles(size)
+	for i, rotor := range m.rotors.rotors {
+		stepCycle := pairs[rng.Intn(len(pairs))]
+		step, cycle := stepCycle[0], stepCycle[1]
+		position := rng.Intn(size/step) * step
+
+		m.rotors.rotors[i] = newRotor(rotor.pathways, position, step, cycle)
+	}
+	return m, m.Verify()
+}
+
+// stepCycles returns the list of step and cycle pairs that satisfy
+// verifyRotor for rotors of the given size, ordered by step then cycle.
+func stepCycles(size int) [][2]int {
+	var pairs [][2]int
+	for step := 1; step < size; step++ {
+		if size%step != 0 {
+			continue
+		}
+		for cycle := 1; cycle <= size/step; cycle++ {
+			if (size/step)%cycle == 0 {
+				pairs = append(pairs, [2]int{step, cycle})
+			}
+		}
+	}
+	return pairs
+}
+
+// keySource is a deterministic rand.Source that expands a key into a stream
+// of random numbers. Blocks of the stream are computed as HMAC-SHA256 of a
+// block counter using the key.
+type keySource struct {
+	mac     []byte
+	key     []byte
+	counter uint64
+}
+
+// newKeySource returns a keySource that expands the given key.
+func newKeySource(key []byte) *keySource {
+	return &keySource{
+		key: key,
+	}
+}
+
+// Int63 returns a non-negative 63-bit integer from the key stream.
+func (s *keySource) Int63() int64 {
+	return int64(s.Uint64() &^ (1 << 63))
+}
+
+// Uint64 returns a 64-bit integer from the key stream.
+func (s *keySource) Uint64() uint64 {
+	if len(s.mac) < 8 {
+		var counter [8]byte
+		binary.BigEndian.PutUint64(counter[:], s.counter)
+		s.counter++
+
+		h := hmac.New(sha256.New, s.key)
+		h.Write(counter[:])
+		s.mac = h.Sum(nil)
+	}
+
+	n := binary.BigEndian.Uint64(s.mac[:8])
+	s.mac = s.mac[8:]
+	return n
+}
+
+// Seed does nothing, a keySource is determined by its key.
+func (s *keySource) Seed(int64) {}
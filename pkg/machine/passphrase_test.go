@@ -0,0 +1,100 @@
+package machine
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testKDFParams are cheap scrypt parameters used to keep tests fast.
+var testKDFParams = KDFParams{
+	N: 1 << 10,
+	R: 8,
+	P: 1,
+}
+
+// TestFromPassphrase tests that machines derived from the same passphrase
+// and salt are identical, and that changing either changes the machine.
+func TestFromPassphrase(t *testing.T) {
+	unexported := cmp.AllowUnexported(
+		Machine{},
+		Rotors{},
+		Rotor{},
+		Plugboard{},
+		Reflector{},
+	)
+
+	m, err := FromPassphrase("correct horse battery staple", "xenigma", 5, testKDFParams)
+	if err != nil {
+		t.Fatalf("failed to derive machine: %v", err)
+	}
+
+	r, err := FromPassphrase("correct horse battery staple", "xenigma", 5, testKDFParams)
+	if err != nil {
+		t.Fatalf("failed to derive machine: %v", err)
+	}
+	if diff := cmp.Diff(*m, *r, unexported); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	for i, test := range []struct {
+		pass string
+		salt string
+	}{
+		{
+			pass: "correct horse battery staple",
+			salt: "xenigma2",
+		},
+		{
+			pass: "correct horse battery stapler",
+			salt: "xenigma",
+		},
+	} {
+		o, err := FromPassphrase(test.pass, test.salt, 5, testKDFParams)
+		if err != nil {
+			t.Fatalf("test %d: failed to derive machine: %v", i, err)
+		}
+		if cmp.Equal(*m, *o, unexported) {
+			t.Errorf("test %d: derived identical machines", i)
+		}
+	}
+}
+
+// TestFromPassphraseEncrypt tests encryption using a derived machine against
+// a known result, to catch changes in the derivation.
+func TestFromPassphraseEncrypt(t *testing.T) {
+	m, err := FromPassphrase("correct horse battery staple", "xenigma", 3, testKDFParams)
+	if err != nil {
+		t.Fatalf("failed to derive machine: %v", err)
+	}
+
+	got, err := m.Encrypt("Hello, world!")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	if want := "musct, xyhdq!"; got != want {
+		t.Errorf("incorrect encryption, want: %s, got: %s", want, got)
+	}
+}
+
+// TestFromPassphraseInvalid tests that invalid arguments produce an error.
+func TestFromPassphraseInvalid(t *testing.T) {
+	for i, test := range []struct {
+		rotors int
+		params KDFParams
+	}{
+		{
+			rotors: 0,
+			params: testKDFParams,
+		},
+		{
+			rotors: 3,
+			params: KDFParams{N: 3, R: 8, P: 1},
+		},
+	} {
+		if _, err := FromPassphrase("pass", "salt", test.rotors, test.params); err == nil {
+			t.Errorf("test %d: want error, got nil", i)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package machine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// position is a line and a column in a config file, both starting at 1.
+type position struct {
+	line, column int
+}
+
+// locate sets the line and column of each error to the position of its
+// field in contents of the given format, or to the position of the closest
+// enclosing field if the field itself isn't in contents.
+func locate(errs ValidationErrors, contents []byte, format Format) {
+	var positions map[string]position
+	switch format {
+	case JSON:
+		positions = jsonPositions(contents)
+	case YAML:
+		positions = yamlPositions(contents)
+	case TOML:
+		positions = tomlPositions(contents)
+	}
+
+	for _, err := range errs {
+		if err.Line != 0 {
+			continue
+		}
+		for path := err.Path; ; path = parentPath(path) {
+			if pos, ok := positions[path]; ok {
+				err.Line, err.Column = pos.line, pos.column
+				break
+			}
+			if path == "" {
+				break
+			}
+		}
+	}
+}
+
+// parentPath returns the path of the field containing the field at path,
+// and an empty string for top level fields.
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		for i := len(path) - 1; i >= 0; i-- {
+			if path[i] != '[' {
+				continue
+			}
+			index := path[i+1 : len(path)-1]
+			if _, err := strconv.Atoi(index); err == nil {
+				return path[:i]
+			}
+			if _, err := strconv.Unquote(index); err == nil && strings.HasPrefix(index, `"`) {
+				return path[:i]
+			}
+		}
+	}
+
+	if dot := strings.LastIndex(path, "."); dot != -1 {
+		return path[:dot]
+	}
+	return ""
+}
+
+// addKey records the position of a key of an object at path. Keys are
+// recorded both as fields and as map keys, since the paths of connections
+// use map keys, and the paths of other fields use field names.
+func addKey(positions map[string]position, path, key string, pos position) {
+	for _, keyPath := range []string{joinPath(path, key), fmt.Sprintf("%s[%s]", path, strconv.Quote(key))} {
+		if _, ok := positions[keyPath]; !ok {
+			positions[keyPath] = pos
+		}
+	}
+}
+
+// offsetPosition returns the position of a byte offset in contents.
+func offsetPosition(contents []byte, offset int) position {
+	if offset > len(contents) {
+		offset = len(contents)
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := contents[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+	return position{line: line, column: column}
+}
+
+// jsonPositions returns the positions of all fields in JSON contents, keyed
+// by their paths. Fields of objects are positioned at their keys, and
+// elements of arrays at their values.
+func jsonPositions(contents []byte) map[string]position {
+	positions := make(map[string]position)
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+
+	// next returns the position of the next token.
+	next := func() position {
+		offset := int(decoder.InputOffset())
+		for offset < len(contents) && strings.IndexByte(" \t\r\n,:", contents[offset]) != -1 {
+			offset++
+		}
+		return offsetPosition(contents, offset)
+	}
+
+	var value func(path string) bool
+	value = func(path string) bool {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				pos := next()
+				key, err := decoder.Token()
+				if err != nil {
+					return false
+				}
+				name, _ := key.(string)
+				addKey(positions, path, name, pos)
+				if !value(joinPath(path, name)) {
+					return false
+				}
+			}
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				elementPath := fmt.Sprintf("%s[%d]", path, i)
+				positions[elementPath] = next()
+				if !value(elementPath) {
+					return false
+				}
+			}
+		default:
+			return true
+		}
+
+		_, err = decoder.Token() // Closing delimiter.
+		return err == nil
+	}
+
+	positions[""] = next()
+	value("")
+	return positions
+}
+
+// yamlPositions returns the positions of all fields in YAML contents, keyed
+// by their paths. Fields of mappings are positioned at their keys, and
+// elements of sequences at their values.
+func yamlPositions(contents []byte) map[string]position {
+	positions := make(map[string]position)
+	var document yaml.Node
+	if err := yaml.Unmarshal(contents, &document); err != nil || len(document.Content) == 0 {
+		return positions
+	}
+
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i]
+				addKey(positions, path, key.Value, position{line: key.Line, column: key.Column})
+				walk(node.Content[i+1], joinPath(path, key.Value))
+			}
+		case yaml.SequenceNode:
+			for i, element := range node.Content {
+				elementPath := fmt.Sprintf("%s[%d]", path, i)
+				positions[elementPath] = position{line: element.Line, column: element.Column}
+				walk(element, elementPath)
+			}
+		}
+	}
+
+	root := document.Content[0]
+	positions[""] = position{line: root.Line, column: root.Column}
+	walk(root, "")
+	return positions
+}
+
+// tomlKeyValue matches a TOML key/value pair, with a bare or quoted key.
+var tomlKeyValue = regexp.MustCompile(`^\s*("(?:[^"\\]|\\.)*"|'[^']*'|[A-Za-z0-9_-]+)\s*=\s*`)
+
+// tomlString matches a basic or literal TOML string.
+var tomlString = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'[^']*'`)
+
+// tomlPositions returns the positions of fields in TOML contents, keyed by
+// their paths. Fields are positioned at their keys, and elements of arrays
+// of strings written on one line at their values. Arrays of tables are
+// positioned at their headers. Other values, such as inline tables, aren't
+// positioned.
+func tomlPositions(contents []byte) map[string]position {
+	positions := map[string]position{"": {line: 1, column: 1}}
+	tables := make(map[string]int)
+	table := ""
+	for i, line := range strings.Split(string(contents), "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := utf8.RuneCountInString(line[:strings.Index(line, trimmed)])
+		pos := position{line: i + 1, column: indent + 1}
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.HasPrefix(trimmed, "[["):
+			name := strings.TrimSpace(strings.Trim(trimmed, "[]"))
+			table = fmt.Sprintf("%s[%d]", name, tables[name])
+			tables[name]++
+			positions[table] = pos
+			if _, ok := positions[name]; !ok {
+				positions[name] = pos
+			}
+		case strings.HasPrefix(trimmed, "["):
+			table = strings.TrimSpace(strings.Trim(trimmed, "[]"))
+			positions[table] = pos
+		default:
+			match := tomlKeyValue.FindStringSubmatch(line)
+			if match == nil {
+				continue
+			}
+			key := match[1]
+			if unquoted, err := strconv.Unquote(key); err == nil {
+				key = unquoted
+			} else {
+				key = strings.Trim(key, "'")
+			}
+			addKey(positions, table, key, pos)
+
+			value := line[len(match[0]):]
+			if !strings.HasPrefix(value, "[") {
+				continue
+			}
+			path := joinPath(table, key)
+			for j, bounds := range tomlString.FindAllStringIndex(value, -1) {
+				offset := len(match[0]) + bounds[0]
+				positions[fmt.Sprintf("%s[%d]", path, j)] = position{
+					line:   i + 1,
+					column: utf8.RuneCountInString(line[:offset]) + 1,
+				}
+			}
+		}
+	}
+	return positions
+}
+
+// yamlLine matches the line number in errors of the yaml package.
+var yamlLine = regexp.MustCompile(`line (\d+)`)
+
+// syntaxErrors returns an error returned when unmarshalling contents of the
+// given format as ValidationErrors, positioned at the error if possible.
+func syntaxErrors(err error, contents []byte, format Format) ValidationErrors {
+	var jsonSyntax *json.SyntaxError
+	var jsonType *json.UnmarshalTypeError
+	var yamlType *yaml.TypeError
+	var tomlParse toml.ParseError
+	switch {
+	case errors.As(err, &jsonSyntax):
+		// Offsets of JSON errors are after the invalid character or value.
+		return ValidationErrors{positioned(err.Error(), offsetPosition(contents, int(jsonSyntax.Offset)-1))}
+	case errors.As(err, &jsonType):
+		return ValidationErrors{positioned(err.Error(), offsetPosition(contents, int(jsonType.Offset)-1))}
+	case errors.As(err, &yamlType):
+		errs := make(ValidationErrors, len(yamlType.Errors))
+		for i, message := range yamlType.Errors {
+			errs[i] = positioned(message, yamlPosition(message))
+		}
+		return errs
+	case format == YAML:
+		return ValidationErrors{positioned(err.Error(), yamlPosition(err.Error()))}
+	case errors.As(err, &tomlParse):
+		pos := offsetPosition(contents, tomlParse.Position.Start)
+		if tomlParse.Position.Line != 0 {
+			pos.line = tomlParse.Position.Line
+		}
+		return ValidationErrors{positioned(err.Error(), pos)}
+	}
+	return ValidationErrors{{Reason: err.Error()}}
+}
+
+// yamlPosition returns the position of the line number in an error message
+// of the yaml package, or a zero position if there's none.
+func yamlPosition(message string) position {
+	match := yamlLine.FindStringSubmatch(message)
+	if match == nil {
+		return position{}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return position{line: line, column: 1}
+}
+
+// positioned returns a ValidationError of the whole config with the given
+// reason, positioned at pos.
+func positioned(reason string, pos position) *ValidationError {
+	return &ValidationError{Reason: reason, Line: pos.line, Column: pos.column}
+}
@@ -0,0 +1,154 @@
+package machine
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestLocate tests that validation errors are positioned at their fields in
+// config files of each format.
+func TestLocate(t *testing.T) {
+	for i, test := range []struct {
+		format   Format
+		contents string
+		path     string
+		line     int
+		column   int
+	}{
+		{
+			format: JSON,
+			contents: `{
+	"mode": "enigma",
+	"rotors": [
+		{"wiring": "III", "position": "a", "ring": "?"},
+		{"wiring": "II", "position": "a"},
+		{"wiring": "I", "position": "a"}
+	],
+	"reflector": "UKW-B",
+	"plugboard": {"pairs": ""}
+}`,
+			path:   "rotors[0].ring",
+			line:   4,
+			column: 38,
+		},
+		{
+			format: JSON,
+			contents: `{
+	"rotors": [{"wiring": "I", "position": "a", "step": 1, "cycle": 26}],
+	"reflector": "UKW-B",
+	"plugboard": {"connections": {"a": "b", "b": "c"}}
+}`,
+			path:   "plugboard.connections",
+			line:   4,
+			column: 16,
+		},
+		{
+			format: YAML,
+			contents: `mode: enigma
+rotors:
+  - wiring: III
+    position: a
+  - wiring: II
+    position: a
+    notches: [e, e]
+  - wiring: I
+    position: a
+reflector: UKW-B
+plugboard:
+  pairs: ""
+`,
+			path:   "rotors[1].notches[1]",
+			line:   7,
+			column: 18,
+		},
+		{
+			format: YAML,
+			contents: `mode: enigma
+rotors:
+  - wiring: III
+    position: a
+  - wiring: II
+    position: a
+reflector: UKW-B
+plugboard:
+  pairs: ""
+`,
+			path:   "rotors",
+			line:   2,
+			column: 1,
+		},
+		{
+			format: TOML,
+			contents: `mode = "enigma"
+reflector = "UKW-B"
+
+[[rotors]]
+wiring = "III"
+position = "a"
+
+[[rotors]]
+wiring = "II"
+position = "a"
+stationary = true
+
+[[rotors]]
+wiring = "I"
+position = "a"
+
+[plugboard]
+pairs = ""
+`,
+			path:   "rotors[1].stationary",
+			line:   11,
+			column: 1,
+		},
+		{
+			format: TOML,
+			contents: `reflector = "UKW-B"
+
+[[rotors]]
+pathways = ["a", "a", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z"]
+position = "a"
+step = 1
+cycle = 26
+
+[plugboard]
+pairs = ""
+`,
+			path:   "rotors[0].pathways[1]",
+			line:   4,
+			column: 18,
+		},
+		{
+			format:   JSON,
+			contents: "{\n\t\"rotors\": [}\n",
+			line:     2,
+			column:   13,
+		},
+		{
+			format:   YAML,
+			contents: "rotors:\n  - wiring: I\n position: a\n",
+			line:     2,
+			column:   1,
+		},
+		{
+			format:   TOML,
+			contents: "reflector = \"UKW-B\"\nrotors = [\n",
+			line:     2,
+			column:   11,
+		},
+	} {
+		_, err := ParseFormat([]byte(test.contents), test.format)
+		var errs ValidationErrors
+		if !errors.As(err, &errs) {
+			t.Errorf("test %d: error is not ValidationErrors: %v", i, err)
+			continue
+		}
+
+		first := errs[0]
+		if first.Path != test.path || first.Line != test.line || first.Column != test.column {
+			t.Errorf("test %d: incorrect position, want: %s %d:%d, got: %s %d:%d (%v)",
+				i, test.path, test.line, test.column, first.Path, first.Line, first.Column, first)
+		}
+	}
+}
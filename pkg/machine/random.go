@@ -0,0 +1,47 @@
+package machine
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// cryptoSource is a rand.Source that reads from crypto/rand. Seeding has no
+// effect on a cryptoSource.
+//
+// Wrapping a cryptoSource in a rand.Rand gives an unbiased Fisher–Yates
+// shuffle, as rand.Rand.Shuffle and rand.Rand.Intn use rejection sampling
+// to produce uniformly distributed bounded integers.
+type cryptoSource struct{}
+
+// Int63 returns a non-negative 63-bit integer read from crypto/rand.
+func (s cryptoSource) Int63() int64 {
+	return int64(s.Uint64() &^ (1 << 63))
+}
+
+// Uint64 returns a 64-bit integer read from crypto/rand. Uint64 panics if
+// crypto/rand fails to provide random bytes.
+func (s cryptoSource) Uint64() uint64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to read from crypto/rand: %v", err))
+	}
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+// Seed does nothing, a cryptoSource can't be seeded.
+func (s cryptoSource) Seed(int64) {}
+
+// SecureSource returns a rand.Source that reads from crypto/rand. It can be
+// used to generate machines that are used as keys, for example using
+// GenerateByteMachine.
+func SecureSource() rand.Source {
+	return cryptoSource{}
+}
+
+// newRand returns a random number generator seeded with the current time.
+func newRand() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
@@ -3,9 +3,12 @@ package machine
 import (
 	"fmt"
 	"math/rand"
+	"strconv"
 )
 
-// Default values for rotor properties.
+// Default values for rotor properties. DefaultCycle is the default cycle of
+// rotors of LatinAlphabet, the default cycle of other rotors is the size of
+// their alphabet.
 const (
 	DefaultPosition = 0
 	DefaultStep     = 1
@@ -15,95 +18,193 @@ const (
 // Rotor represents a mechanical rotor used in xenigma. A rotor contains connections
 // used to make electric pathways and generate a path through the machine.
 type Rotor struct {
-	pathways   [alphabetSize]int // Connections that form electric pathways.
-	position   int               // Current position.
-	takenSteps int               // Number of taken steps.
-	step       int               // Size of shift between steps, in characters.
-	cycle      int               // Number of steps considered a full cycle.
+	pathways      []int // Connections that form electric pathways.
+	position      int   // Current position.
+	takenSteps    int   // Number of taken steps.
+	step          int   // Size of shift between steps, in characters.
+	cycle         int   // Number of steps considered a full cycle.
+	startPosition int   // Position at creation, used by Reset.
+	startSteps    int   // Number of taken steps at creation, used by Reset.
+	ring          int   // Ring setting, used in EnigmaMode.
+	notches       []int // Turnover notch positions, used in EnigmaMode.
+	stationary    bool  // Whether the rotor never moves.
 }
 
 // NewRotor returns a pointer to a new, initialized Rotor, and an error if
-// given fields are invalid.
-func NewRotor(pathways [alphabetSize]int, position, step, cycle int) (*Rotor, error) {
+// given fields are invalid. The size of the rotor is the number of pathways,
+// which must match the size of the alphabet of the machine using the rotor.
+func NewRotor(pathways []int, position, step, cycle int) (*Rotor, error) {
 	if err := verifyRotor(pathways, position, step, cycle); err != nil {
 		return nil, err
 	}
 
+	return newRotor(append([]int(nil), pathways...), position, step, cycle), nil
+}
+
+// newRotor returns a pointer to a new Rotor with the given fields. Fields
+// are not verified.
+func newRotor(pathways []int, position, step, cycle int) *Rotor {
+	step %= len(pathways)
+	takenSteps := (position / step) % cycle
 	return &Rotor{
-		pathways:   pathways,
-		position:   position,
-		takenSteps: (position / (step % alphabetSize)) % cycle,
-		step:       step % alphabetSize,
-		cycle:      cycle,
-	}, nil
+		pathways:      pathways,
+		position:      position,
+		takenSteps:    takenSteps,
+		step:          step,
+		cycle:         cycle,
+		startPosition: position,
+		startSteps:    takenSteps,
+	}
 }
 
-// GenerateRotor generates and returns a rotor with random config.
+// GenerateRotor generates and returns a rotor of LatinAlphabet with random
+// config.
 func GenerateRotor() *Rotor {
-	var pathways [alphabetSize]int
-	for i := 0; i < alphabetSize; i++ {
+	return generateRotor(LatinAlphabet.Size(), newRand())
+}
+
+// generateRotor generates and returns a rotor of the given size with random
+// config using the given random number generator.
+func generateRotor(size int, rng *rand.Rand) *Rotor {
+	pathways := make([]int, size)
+	for i := 0; i < size; i++ {
 		pathways[i] = i
 	}
 
-	rand.Shuffle(
-		alphabetSize,
+	rng.Shuffle(
+		size,
 		func(j, k int) {
 			pathways[j], pathways[k] = pathways[k], pathways[j]
 		},
 	)
 
-	position := rand.Intn(alphabetSize)
-	return &Rotor{
-		pathways:   pathways,
-		position:   position,
-		takenSteps: (position / (DefaultStep % alphabetSize)) % DefaultCycle,
-		step:       DefaultStep,
-		cycle:      DefaultCycle,
-	}
+	return newRotor(pathways, rng.Intn(size), DefaultStep, size)
 }
 
-// takeStep moves rotor one step forward.
-func (r *Rotor) takeStep() {
-	r.position = (r.position + r.step) % alphabetSize
+// TakeStep moves rotor one step forward, shifting its position by its step
+// size. TakeStep is used by steppers to move rotors, and moves stationary
+// rotors as well, so steppers should skip them.
+func (r *Rotor) TakeStep() {
+	r.position = (r.position + r.step) % len(r.pathways)
 	r.takenSteps = (r.takenSteps + 1) % r.cycle
 }
 
+// advance moves rotor n steps forward, and returns the number of full
+// cycles completed while moving.
+func (r *Rotor) advance(n uint64) uint64 {
+	size := uint64(len(r.pathways))
+	cycle := uint64(r.cycle)
+	takenSteps := uint64(r.takenSteps)
+	cycles := n/cycle + (takenSteps+n%cycle)/cycle
+
+	r.position = int((uint64(r.position) + (n%size)*uint64(r.step)) % size)
+	r.takenSteps = int((takenSteps + n%cycle) % cycle)
+	return cycles
+}
+
 // Verify verifies rotor's current configuration, returns an error if rotor's
 // fields are incorrect or incompatible.
 func (r *Rotor) Verify() error {
-	return verifyRotor(r.pathways, r.position, r.step, r.cycle)
+	errs := verifyRotorFields(r.pathways, r.position, r.step, r.cycle, strconv.Itoa)
+	errs = append(errs, verifyRingFields(len(r.pathways), r.ring, r.notches, strconv.Itoa)...)
+	return errs.err()
 }
 
 // verifyRotor verifies given pathway connections, position, step size, and
 // cycle size, and returns an error if given values are incorrect or incompatible.
-func verifyRotor(pathways [alphabetSize]int, position, step, cycle int) (err error) {
+// The size of the rotor is the number of pathways.
+func verifyRotor(pathways []int, position, step, cycle int) error {
+	return verifyRotorFields(pathways, position, step, cycle, strconv.Itoa).err()
+}
+
+// verifyRotorFields is similar to verifyRotor, but returns all invalid
+// fields, using name to describe pathways and positions.
+func verifyRotorFields(pathways []int, position, step, cycle int, name func(int) string) ValidationErrors {
+	if len(pathways) == 0 {
+		return ValidationErrors{{Path: "pathways", Reason: "no electric pathways given"}}
+	}
+
+	errs := verifyPermutation("pathways", "pathway", pathways, strconv.Itoa, name)
+	return append(errs, verifyRotorSettings(len(pathways), position, step, cycle, name)...)
+}
+
+// verifyRotorSettings verifies position, step size, and cycle size of a
+// rotor of the given size, and returns all invalid fields, using name to
+// describe positions.
+func verifyRotorSettings(size, position, step, cycle int, name func(int) string) (errs ValidationErrors) {
+	if step <= 0 {
+		errs = append(errs, &ValidationError{Path: "step", Reason: "invalid step", Got: strconv.Itoa(step), Want: "a positive number"})
+	} else if step >= size {
+		errs = append(errs, &ValidationError{Path: "step", Reason: "invalid step", Got: strconv.Itoa(step), Want: fmt.Sprintf("1 to %d", size-1)})
+	}
+	if cycle <= 0 {
+		errs = append(errs, &ValidationError{Path: "cycle", Reason: "invalid cycle", Got: strconv.Itoa(cycle), Want: "a positive number"})
+	}
+	if len(errs) != 0 {
+		return errs
+	}
+
 	switch {
-	case !zeroToNSlice(pathways[:]):
-		err = fmt.Errorf("electric pathways are incorrect")
-	case step <= 0:
-		err = fmt.Errorf("invalid step: %d", step)
-	case cycle <= 0:
-		err = fmt.Errorf("invalid cycle: %d", cycle)
-	case (position)%step != 0 || position < 0 || position > alphabetSize:
-		err = fmt.Errorf("invalid position: %d", position)
-	case ((alphabetSize) % (step * cycle)) != 0:
-		err = fmt.Errorf("cycle and step are incompatible, some collisions may occur")
+	case position < 0 || position > size:
+		errs = append(errs, &ValidationError{Path: "position", Reason: "invalid position", Got: strconv.Itoa(position), Want: fmt.Sprintf("0 to %d", size-1)})
+	case position%step != 0:
+		errs = append(errs, &ValidationError{Path: "position", Reason: "invalid position", Got: describe(position, size, name), Want: fmt.Sprintf("a multiple of step %d", step)})
 	}
-	return err
+	if size%(step*cycle) != 0 {
+		errs = append(errs, &ValidationError{
+			Path:   "cycle",
+			Reason: "cycle and step are incompatible, some collisions may occur",
+			Got:    fmt.Sprintf("step %d and cycle %d", step, cycle),
+			Want:   fmt.Sprintf("step times cycle dividing %d", size),
+		})
+	}
+	return errs
 }
 
-// UseDefaults sets all rotor's fields, except pathways, to their default
-// values. Defaults are 'a' for position, 1 for step, and 26 for cycle.
+// describe returns the description of a value using name, or the value as
+// a number if it isn't one of 0 through size-1.
+func describe(value, size int, name func(int) string) string {
+	if value < 0 || value >= size {
+		return strconv.Itoa(value)
+	}
+	return name(value)
+}
+
+// UseDefaults sets all rotor's fields, except pathways, notches, and whether
+// the rotor is stationary, to their default values. Defaults are the first
+// character of the alphabet for position and ring setting, 1 for step, and
+// the size of the alphabet (26 for LatinAlphabet) for cycle.
 func (r *Rotor) UseDefaults() {
-	r.position = DefaultPosition
-	r.takenSteps = (DefaultPosition / (DefaultStep % alphabetSize)) % DefaultCycle
-	r.step = DefaultStep
-	r.cycle = DefaultCycle
+	notches, stationary := r.notches, r.stationary
+	*r = *newRotor(r.pathways, DefaultPosition, DefaultStep, len(r.pathways))
+	r.notches, r.stationary = notches, stationary
+}
+
+// Reset returns rotor to its position at creation.
+func (r *Rotor) Reset() {
+	r.position = r.startPosition
+	r.takenSteps = r.startSteps
 }
 
-// Pathways returns rotor's pathway connections.
-func (r *Rotor) Pathways() [alphabetSize]int {
-	return r.pathways
+// clone returns a copy of the rotor.
+func (r *Rotor) clone() *Rotor {
+	clone := *r
+	clone.pathways = append([]int(nil), r.pathways...)
+	if r.notches != nil {
+		clone.notches = append([]int(nil), r.notches...)
+	}
+	return &clone
+}
+
+// Pathways returns a copy of rotor's pathway connections.
+func (r *Rotor) Pathways() []int {
+	return append([]int(nil), r.pathways...)
+}
+
+// Size returns the number of rotor's pathways, which is the size of the
+// alphabet it's used with.
+func (r *Rotor) Size() int {
+	return len(r.pathways)
 }
 
 // Position returns rotor's current position.
@@ -111,6 +212,12 @@ func (r *Rotor) Position() int {
 	return r.position
 }
 
+// TakenSteps returns the number of steps rotor has taken in its current
+// cycle, which is 0 when the rotor completes a full cycle.
+func (r *Rotor) TakenSteps() int {
+	return r.takenSteps
+}
+
 // Step returns rotor's step size. Step represents the number of positions
 // a rotor jumps when moving one step forward, and defaults to 1.
 func (r *Rotor) Step() int {
@@ -123,3 +230,23 @@ func (r *Rotor) Step() int {
 func (r *Rotor) Cycle() int {
 	return r.cycle
 }
+
+// Ring returns rotor's ring setting. The ring setting shifts the wiring of
+// a rotor relative to its position, and is only used in EnigmaMode.
+func (r *Rotor) Ring() int {
+	return r.ring
+}
+
+// Notches returns a copy of rotor's turnover notch positions. When a rotor
+// is at a notch it moves the next rotor, notches are only used in
+// EnigmaMode.
+func (r *Rotor) Notches() []int {
+	return append([]int(nil), r.notches...)
+}
+
+// Stationary returns true if the rotor never moves, like the Greek wheel of
+// an Enigma M4. Stationary rotors are skipped when rotors move, so the rotor
+// after a stationary rotor is moved by the rotor before it.
+func (r *Rotor) Stationary() bool {
+	return r.stationary
+}
@@ -136,6 +136,91 @@ func TestTakeSteps(t *testing.T) {
 	}
 }
 
+// TestAdvance compares advancing rotors with stepping them one step at a
+// time using different step and cycle sizes.
+func TestAdvance(t *testing.T) {
+	for i, test := range []struct {
+		setting []int
+		steps   []int
+		cycles  []int
+	}{
+		{
+			setting: []int{0, 0, 0},
+			steps:   []int{1, 1, 1},
+			cycles:  []int{26, 26, 26},
+		},
+		{
+			setting: []int{1, 0, 0},
+			steps:   []int{1, 1, 1},
+			cycles:  []int{2, 2, 2},
+		},
+		{
+			setting: []int{0, 13, 0},
+			steps:   []int{13, 13, 13},
+			cycles:  []int{2, 2, 2},
+		},
+		{
+			setting: []int{4, 0, 2, 0},
+			steps:   []int{2, 1, 2, 1},
+			cycles:  []int{13, 1, 13, 26},
+		},
+		{
+			setting: []int{25, 25, 25, 25, 25},
+			steps:   []int{1, 1, 1, 1, 1},
+			cycles:  []int{13, 26, 2, 1, 26},
+		},
+	} {
+		for _, n := range []uint64{0, 1, 25, 26, 27, 1000, 26 * 26 * 26} {
+			stepped := newTestRotors(t, test.setting, test.steps, test.cycles)
+			for k := uint64(0); k < n; k++ {
+				stepped.takeStep()
+			}
+
+			advanced := newTestRotors(t, test.setting, test.steps, test.cycles)
+			advanced.advance(n)
+
+			if diff := cmp.Diff(stepped, advanced, cmp.AllowUnexported(Rotors{}, Rotor{})); diff != "" {
+				t.Errorf("test %d, n %d: mismatch (-want +got):\n%s", i, n, diff)
+			}
+		}
+	}
+}
+
+// TestStationary tests that stationary rotors don't move, and that the
+// rotor after a stationary rotor is moved by the rotor before it.
+func TestStationary(t *testing.T) {
+	rotors := newTestRotors(t, []int{0, 5, 0}, []int{1, 1, 1}, []int{26, 26, 26})
+	rotors.rotors[1].stationary = true
+
+	for i := 0; i < 26*3; i++ {
+		rotors.takeStep()
+	}
+	if diff := cmp.Diff([]int{0, 5, 3}, rotors.Setting()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	advanced := newTestRotors(t, []int{0, 5, 0}, []int{1, 1, 1}, []int{26, 26, 26})
+	advanced.rotors[1].stationary = true
+	advanced.advance(26 * 3)
+	if diff := cmp.Diff(rotors, advanced, cmp.AllowUnexported(Rotors{}, Rotor{})); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestAdvanceOverflow tests that advancing by very large counts doesn't
+// overflow.
+func TestAdvanceOverflow(t *testing.T) {
+	r := newTestRotors(t, []int{25, 0}, []int{1, 1}, []int{26, 26})
+	r.advance(^uint64(0))
+
+	// 2^64-1 = 26*709490156681136600 + 15, so the first rotor moves 15
+	// positions from 25, and completes 709490156681136601 cycles.
+	want := []int{14, int(709490156681136601 % 26)}
+	if diff := cmp.Diff(want, r.Setting()); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
 // BenchmarkTakeStep benchmarks the stepping of a 1000-rotor machine.
 func BenchmarkTakeStep(b *testing.B) {
 	r := GenerateRotors(1000)
@@ -176,15 +261,15 @@ func TestNewRotors(t *testing.T) {
 		if test.shouldErr && err == nil {
 			t.Errorf("test %d: want error, got nil", i)
 		} else if !test.shouldErr && err != nil {
-			t.Errorf("test %d: want nil, got %w", i, err)
+			t.Errorf("test %d: want nil, got %v", i, err)
 		}
 	}
 }
 
 // TestNewRotor test rotor validation.
 func TestNewRotor(t *testing.T) {
-	var pathways [alphabetSize]int
-	for i := 0; i < alphabetSize; i++ {
+	pathways := make([]int, LatinAlphabet.Size())
+	for i := 0; i < LatinAlphabet.Size(); i++ {
 		pathways[i] = i
 	}
 
@@ -229,15 +314,15 @@ func TestNewRotor(t *testing.T) {
 		if test.shouldErr && err == nil {
 			t.Errorf("test %d: want error, got nil", i)
 		} else if !test.shouldErr && err != nil {
-			t.Errorf("test %d: want nil, got %w", i, err)
+			t.Errorf("test %d: want nil, got %v", i, err)
 		}
 	}
 }
 
 // TestStepCycle tests step-cycle compatability.
 func TestStepCycle(t *testing.T) {
-	var pathways [alphabetSize]int
-	for i := 0; i < alphabetSize; i++ {
+	pathways := make([]int, LatinAlphabet.Size())
+	for i := 0; i < LatinAlphabet.Size(); i++ {
 		pathways[i] = i
 	}
 
@@ -279,7 +364,7 @@ func TestStepCycle(t *testing.T) {
 		{
 			step:      26,
 			cycle:     1,
-			shouldErr: false,
+			shouldErr: true,
 		},
 		{
 			step:      2,
@@ -311,7 +396,7 @@ func TestStepCycle(t *testing.T) {
 		if test.shouldErr && err == nil {
 			t.Errorf("test %d: want error, got nil", i)
 		} else if !test.shouldErr && err != nil {
-			t.Errorf("test %d: want nil, got %w", i, err)
+			t.Errorf("test %d: want nil, got %v", i, err)
 		}
 	}
 }
@@ -331,8 +416,8 @@ func newTestRotors(t *testing.T, setting []int, steps []int, cycles []int) *Roto
 // for testing.
 func newRotorArr(t *testing.T, setting []int, steps []int, cycles []int) []*Rotor {
 	t.Helper()
-	var pathways [alphabetSize]int
-	for i := 0; i < alphabetSize; i++ {
+	pathways := make([]int, LatinAlphabet.Size())
+	for i := 0; i < LatinAlphabet.Size(); i++ {
 		pathways[i] = i
 	}
 
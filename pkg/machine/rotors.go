@@ -2,6 +2,8 @@ package machine
 
 import (
 	"fmt"
+	"math/rand"
+	"strconv"
 )
 
 // Rotors is a list of rotors used as a part of a machine.
@@ -13,17 +15,8 @@ type Rotors struct {
 // NewRotors returns a new, initialized Rotors pointer, and an error if given
 // rotor list is invalid.
 func NewRotors(rotors []*Rotor) (*Rotors, error) {
-	if len(rotors) == 0 {
-		return nil, fmt.Errorf("no rotors given")
-	}
-
-	for i, rotor := range rotors {
-		if rotor == nil {
-			return nil, fmt.Errorf("rotor %d doesn't exist", i)
-		}
-		if err := rotor.Verify(); err != nil {
-			return nil, fmt.Errorf("rotor %d: %w", i, err)
-		}
+	if err := verifyRotors(rotors).err(); err != nil {
+		return nil, err
 	}
 
 	return &Rotors{
@@ -32,11 +25,18 @@ func NewRotors(rotors []*Rotor) (*Rotors, error) {
 	}, nil
 }
 
-// GenerateRotors returns a list of randomly generated rotors.
+// GenerateRotors returns a list of randomly generated rotors of
+// LatinAlphabet.
 func GenerateRotors(count int) *Rotors {
+	return generateRotors(count, LatinAlphabet.Size(), newRand())
+}
+
+// generateRotors returns a list of rotors of the given size generated using
+// the given random number generator.
+func generateRotors(count, size int, rng *rand.Rand) *Rotors {
 	rotors := make([]*Rotor, count)
 	for i := 0; i < count; i++ {
-		rotors[i] = GenerateRotor()
+		rotors[i] = generateRotor(size, rng)
 	}
 
 	return &Rotors{
@@ -53,28 +53,64 @@ func (r *Rotors) Rotor(i int) (*Rotor, error) {
 	return r.rotors[i], nil
 }
 
-// takeStep moves the rotors one step forward.
+// takeStep moves the rotors one step forward like an odometer. Stationary
+// rotors don't move.
 func (r *Rotors) takeStep() {
-	for i, rotor := range r.rotors {
-		if i != 0 && (r.rotors[i-1].takenSteps != 0) { // Previous rotor didn't complete a cycle.
+	OdometerStepper{}.Step(r.rotors)
+}
+
+// advance moves the rotors n steps forward. The result is the same as
+// calling takeStep n times, but is computed in O(count) time.
+func (r *Rotors) advance(n uint64) {
+	for _, rotor := range r.rotors {
+		if n == 0 {
 			break
 		}
-		rotor.takeStep()
+		if rotor.stationary {
+			continue
+		}
+		n = rotor.advance(n)
 	}
 }
 
 // Verify verifies that rotors' are valid, and returns an error otherwise.
 func (r *Rotors) Verify() error {
-	if len(r.rotors) == 0 || r.count != len(r.rotors) {
-		return fmt.Errorf("invalid number of rotors")
+	if r.count != len(r.rotors) {
+		return &ValidationError{Path: "rotors", Reason: "invalid number of rotors", Got: strconv.Itoa(r.count), Want: strconv.Itoa(len(r.rotors))}
 	}
+	return verifyRotors(r.rotors).err()
+}
 
-	for i, rotor := range r.rotors {
-		if err := rotor.Verify(); err != nil {
-			return fmt.Errorf("rotor %d: %w", i, err)
+// verifyRotors verifies each of the given rotors, and that all rotors have
+// the same size, and returns all invalid fields.
+func verifyRotors(rotors []*Rotor) (errs ValidationErrors) {
+	if len(rotors) == 0 {
+		return ValidationErrors{{Path: "rotors", Reason: "no rotors given"}}
+	}
+
+	for i, rotor := range rotors {
+		path := fmt.Sprintf("rotors[%d]", i)
+		if rotor == nil {
+			errs = append(errs, &ValidationError{Path: path, Reason: "rotor doesn't exist"})
+			continue
+		}
+		errs.add(path, rotor.Verify())
+		if rotors[0] != nil && rotor.Size() != rotors[0].Size() {
+			errs = append(errs, &ValidationError{
+				Path:   path + ".pathways",
+				Reason: "size doesn't match size of rotor 0",
+				Got:    strconv.Itoa(rotor.Size()),
+				Want:   strconv.Itoa(rotors[0].Size()),
+			})
 		}
 	}
-	return nil
+	return errs
+}
+
+// size returns the size of the rotors, which is the number of pathways of
+// each rotor.
+func (r *Rotors) size() int {
+	return r.rotors[0].Size()
 }
 
 // UseDefaults sets all fields of each rotor to, except pathways, to their default
@@ -85,6 +121,26 @@ func (r *Rotors) UseDefaults() {
 	}
 }
 
+// Reset returns all rotors to their positions at creation.
+func (r *Rotors) Reset() {
+	for _, rotor := range r.rotors {
+		rotor.Reset()
+	}
+}
+
+// clone returns a deep copy of the rotors.
+func (r *Rotors) clone() *Rotors {
+	rotors := make([]*Rotor, len(r.rotors))
+	for i, rotor := range r.rotors {
+		rotors[i] = rotor.clone()
+	}
+
+	return &Rotors{
+		rotors: rotors,
+		count:  r.count,
+	}
+}
+
 // Setting returns rotors' current setting. A setting is a list containing the current
 // position of each rotor.
 func (r *Rotors) Setting() []int {
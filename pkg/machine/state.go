@@ -0,0 +1,123 @@
+package machine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// State is a snapshot of the state of a machine's rotors. A State contains
+// the position and the number of taken steps of every rotor, and can be used
+// to restore a machine to the exact point at which the snapshot was taken.
+//
+// State implements encoding.TextMarshaler and encoding.TextUnmarshaler. The
+// text form of a State is a comma separated list of position:takenSteps
+// pairs, one for each rotor, for example "3:3,0:0,25:25", which is also
+// used when a State is encoded as JSON.
+type State struct {
+	Rotors []RotorState
+}
+
+// RotorState is the state of one rotor.
+type RotorState struct {
+	Position   int
+	TakenSteps int
+}
+
+// State returns a snapshot of the current state of machine's rotors, which
+// is empty if machine has no rotors.
+func (m *Machine) State() State {
+	if m.rotors == nil {
+		return State{}
+	}
+
+	state := State{
+		Rotors: make([]RotorState, m.rotors.count),
+	}
+	for i, rotor := range m.rotors.rotors {
+		state.Rotors[i] = RotorState{
+			Position:   rotor.position,
+			TakenSteps: rotor.takenSteps,
+		}
+	}
+	return state
+}
+
+// SetState restores machine's rotors to the given state, and returns an
+// error if the state doesn't fit the machine. Machine is not modified if an
+// error is returned.
+func (m *Machine) SetState(state State) error {
+	if m.rotors == nil {
+		return fmt.Errorf("invalid state: machine has no rotors")
+	}
+	if len(state.Rotors) != m.rotors.count {
+		return fmt.Errorf("invalid state: expected %d rotors, got %d", m.rotors.count, len(state.Rotors))
+	}
+
+	for i, rotor := range m.rotors.rotors {
+		if err := rotor.verifyState(state.Rotors[i]); err != nil {
+			return fmt.Errorf("invalid state: rotor %d: %w", i, err)
+		}
+	}
+
+	for i, rotor := range m.rotors.rotors {
+		rotor.position = state.Rotors[i].Position
+		rotor.takenSteps = state.Rotors[i].TakenSteps
+	}
+	return nil
+}
+
+// verifyState returns an error if the given state can't be reached by the
+// rotor. A rotor's taken steps are determined by its position, so a state
+// is only reachable if its taken steps match its position.
+func (r *Rotor) verifyState(state RotorState) error {
+	switch {
+	case state.Position < 0 || state.Position >= r.Size() || state.Position%r.step != 0:
+		return fmt.Errorf("invalid position: %d", state.Position)
+	case state.TakenSteps != (state.Position/r.step)%r.cycle:
+		return fmt.Errorf("invalid taken steps: %d, want %d at position %d", state.TakenSteps, (state.Position/r.step)%r.cycle, state.Position)
+	}
+	return nil
+}
+
+// MarshalText returns the text form of the state.
+func (s State) MarshalText() ([]byte, error) {
+	rotors := make([]string, len(s.Rotors))
+	for i, rotor := range s.Rotors {
+		rotors[i] = fmt.Sprintf("%d:%d", rotor.Position, rotor.TakenSteps)
+	}
+	return []byte(strings.Join(rotors, ",")), nil
+}
+
+// UnmarshalText parses the text form of a state.
+func (s *State) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		return fmt.Errorf("empty state")
+	}
+
+	pairs := strings.Split(string(text), ",")
+	rotors := make([]RotorState, len(pairs))
+	for i, pair := range pairs {
+		fields := strings.Split(pair, ":")
+		if len(fields) != 2 {
+			return fmt.Errorf("invalid rotor state %q", pair)
+		}
+
+		position, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid rotor position %q", fields[0])
+		}
+		takenSteps, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid rotor taken steps %q", fields[1])
+		}
+
+		rotors[i] = RotorState{
+			Position:   position,
+			TakenSteps: takenSteps,
+		}
+	}
+
+	s.Rotors = rotors
+	return nil
+}
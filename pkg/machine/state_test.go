@@ -0,0 +1,135 @@
+package machine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestStateRestore tests that restoring a snapshot taken mid-message produces
+// the same encryption of the rest of the message.
+func TestStateRestore(t *testing.T) {
+	m, err := Read("../../test-data/config-3.json")
+	if err != nil {
+		t.Fatalf("failed to read machine: %v", err)
+	}
+
+	if _, err := m.Encrypt("The first half of a message,"); err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	state := m.State()
+
+	want, err := m.Encrypt(" and the second half.")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	m.Reset()
+	if err := m.SetState(state); err != nil {
+		t.Fatalf("failed to set state: %v", err)
+	}
+
+	got, err := m.Encrypt(" and the second half.")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	if got != want {
+		t.Errorf("incorrect encryption, want: %s, got: %s", want, got)
+	}
+}
+
+// TestStateMarshal tests text and JSON round trips of a State.
+func TestStateMarshal(t *testing.T) {
+	state := State{
+		Rotors: []RotorState{
+			{Position: 3, TakenSteps: 3},
+			{Position: 0, TakenSteps: 0},
+			{Position: 25, TakenSteps: 12},
+		},
+	}
+
+	text, err := state.MarshalText()
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if string(text) != "3:3,0:0,25:12" {
+		t.Errorf("incorrect text, want: %s, got: %s", "3:3,0:0,25:12", text)
+	}
+
+	var fromText State
+	if err := fromText.UnmarshalText(text); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if diff := cmp.Diff(state, fromText); diff != "" {
+		t.Errorf("text mismatch (-want +got):\n%s", diff)
+	}
+
+	contents, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	if string(contents) != `"3:3,0:0,25:12"` {
+		t.Errorf("incorrect JSON, want: %s, got: %s", `"3:3,0:0,25:12"`, contents)
+	}
+
+	var fromJSON State
+	if err := json.Unmarshal(contents, &fromJSON); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if diff := cmp.Diff(state, fromJSON); diff != "" {
+		t.Errorf("JSON mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestSetStateInvalid tests that states that don't fit a machine, or can't
+// be reached by its rotors, are rejected.
+func TestSetStateInvalid(t *testing.T) {
+	m, err := Read("../../test-data/config-1.json")
+	if err != nil {
+		t.Fatalf("failed to read machine: %v", err)
+	}
+
+	for i, text := range []string{
+		"0:0,0:0",
+		"0:0,0:0,0:0,0:0",
+		"0:0,26:0,0:0",
+		"0:0,-1:0,0:0",
+		"0:0,0:26,0:0",
+		"0:5,0:0,0:0",
+		"3:3,0:0,25:12",
+	} {
+		var state State
+		if err := state.UnmarshalText([]byte(text)); err != nil {
+			t.Fatalf("test %d: failed to unmarshal: %v", i, err)
+		}
+		if err := m.SetState(state); err == nil {
+			t.Errorf("test %d: want error, got nil", i)
+		}
+	}
+
+	if err := new(Machine).SetState(State{}); err == nil {
+		t.Errorf("machine without rotors: want error, got nil")
+	}
+	if state := new(Machine).State(); len(state.Rotors) != 0 {
+		t.Errorf("machine without rotors: want empty state, got %v", state)
+	}
+
+	for i, text := range []string{"0:0,0:0,0:0", "3:3,0:0,25:25"} {
+		var state State
+		if err := state.UnmarshalText([]byte(text)); err != nil {
+			t.Fatalf("test %d: failed to unmarshal: %v", i, err)
+		}
+		if err := m.SetState(state); err != nil {
+			t.Errorf("test %d: want nil, got %v", i, err)
+		}
+	}
+
+	for i, text := range []string{"", "0", "0:a", "a:0", "0:0,"} {
+		var state State
+		if err := state.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("test %d: want error, got nil", i)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package machine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Stepper moves a machine's rotors one step forward each time a character
+// is encrypted. Step is given all of the machine's rotors, ordered from the
+// first rotor to receive the signal to the last, and should not move
+// stationary rotors. A Stepper must be deterministic, rotors in the same
+// state must always move the same way, so that messages can be decrypted.
+// Rotors are moved using Rotor.TakeStep.
+type Stepper interface {
+	Step(rotors []*Rotor)
+}
+
+// OdometerStepper moves rotors like an odometer. The first rotor moves every
+// step, and each other rotor moves when the rotor before it completes a full
+// cycle. OdometerStepper is the default stepper of XenigmaMode.
+type OdometerStepper struct{}
+
+// EnigmaStepper moves rotors like an Enigma machine. The first rotor moves
+// every step, each other rotor moves when the rotor before it is at a
+// notch, and also moves together with the rotor after it when it is at a
+// notch itself, which is the double-step anomaly of the middle rotor.
+// EnigmaStepper is the default stepper of EnigmaMode.
+type EnigmaStepper struct{}
+
+// TypexStepper moves rotors like a Typex machine, using rotors with
+// multiple notches. The first rotor moves every step, and each other rotor
+// moves when the rotor before it moves from a notch. Unlike EnigmaStepper,
+// rotors don't double-step.
+type TypexStepper struct{}
+
+// KeystreamStepper moves rotors irregularly, using a keystream read from
+// the rotors' wiring. The first rotor moves every step, and each other rotor
+// moves when the pathway at the current position of the rotor before it
+// is odd. The movement of rotors depends on their wiring, so it can't be
+// predicted without knowing the machine's configuration.
+type KeystreamStepper struct{}
+
+// Names of built-in steppers, as used in config files.
+const (
+	odometerName  = "odometer"
+	enigmaName    = "enigma"
+	typexName     = "typex"
+	keystreamName = "keystream"
+)
+
+// ParseStepper returns the built-in stepper with the given name, which is
+// one of "odometer", "enigma", "typex", and "keystream", and an error if no
+// such stepper exists.
+func ParseStepper(name string) (Stepper, error) {
+	switch strings.ToLower(name) {
+	case odometerName:
+		return OdometerStepper{}, nil
+	case enigmaName:
+		return EnigmaStepper{}, nil
+	case typexName:
+		return TypexStepper{}, nil
+	case keystreamName:
+		return KeystreamStepper{}, nil
+	}
+	return nil, &ValidationError{
+		Reason: "unknown stepper",
+		Got:    strconv.Quote(name),
+		Want:   fmt.Sprintf("one of %q, %q, %q, or %q", odometerName, enigmaName, typexName, keystreamName),
+	}
+}
+
+// stepperName returns the name of a built-in stepper, and false if stepper
+// is not built-in.
+func stepperName(stepper Stepper) (string, bool) {
+	switch stepper.(type) {
+	case OdometerStepper:
+		return odometerName, true
+	case EnigmaStepper:
+		return enigmaName, true
+	case TypexStepper:
+		return typexName, true
+	case KeystreamStepper:
+		return keystreamName, true
+	}
+	return "", false
+}
+
+// defaultStepper returns the stepper used by machines in the given mode
+// that don't specify one.
+func defaultStepper(mode Mode) Stepper {
+	if mode == EnigmaMode {
+		return EnigmaStepper{}
+	}
+	return OdometerStepper{}
+}
+
+// Step moves the rotors one step forward like an odometer.
+func (OdometerStepper) Step(rotors []*Rotor) {
+	for _, rotor := range rotors {
+		if rotor.stationary {
+			continue
+		}
+		rotor.TakeStep()
+		if rotor.takenSteps != 0 { // Rotor didn't complete a cycle.
+			break
+		}
+	}
+}
+
+// Step moves the rotors one step forward like an Enigma machine.
+func (EnigmaStepper) Step(rotors []*Rotor) {
+	moving := movingRotors(rotors)
+	atNotch := make([]bool, len(moving))
+	for i, rotor := range moving {
+		atNotch[i] = rotor.atNotch()
+	}
+
+	for i, rotor := range moving {
+		last := i == len(moving)-1
+		if i == 0 || atNotch[i-1] || (!last && atNotch[i]) {
+			rotor.TakeStep()
+		}
+	}
+}
+
+// Step moves the rotors one step forward like a Typex machine.
+func (TypexStepper) Step(rotors []*Rotor) {
+	for _, rotor := range movingRotors(rotors) {
+		atNotch := rotor.atNotch()
+		rotor.TakeStep()
+		if !atNotch {
+			break
+		}
+	}
+}
+
+// Step moves the rotors one step forward irregularly.
+func (KeystreamStepper) Step(rotors []*Rotor) {
+	moving := movingRotors(rotors)
+	keystream := make([]bool, len(moving))
+	for i, rotor := range moving {
+		keystream[i] = rotor.pathways[rotor.position]%2 != 0
+	}
+
+	for i, rotor := range moving {
+		if i == 0 || keystream[i-1] {
+			rotor.TakeStep()
+		}
+	}
+}
+
+// verify returns an error if the rotors can't be moved by an EnigmaStepper.
+func (EnigmaStepper) verify(rotors []*Rotor) error {
+	return verifyNotches(rotors)
+}
+
+// verify returns an error if the rotors can't be moved by a TypexStepper.
+func (TypexStepper) verify(rotors []*Rotor) error {
+	return verifyNotches(rotors)
+}
+
+// verifyNotches returns an error if any of the moving rotors, except for the
+// last, doesn't have a notch.
+func verifyNotches(rotors []*Rotor) error {
+	var errs ValidationErrors
+	last := -1
+	for i, rotor := range rotors {
+		if !rotor.stationary {
+			last = i
+		}
+	}
+
+	for i, rotor := range rotors {
+		if rotor.stationary || i == last {
+			continue
+		}
+		if len(rotor.notches) == 0 {
+			errs = append(errs, &ValidationError{
+				Path:   fmt.Sprintf("rotors[%d].notches", i),
+				Reason: "notch-driven stepping requires at least one notch",
+			})
+		}
+	}
+	return errs.err()
+}
+
+// movingRotors returns the rotors that aren't stationary.
+func movingRotors(rotors []*Rotor) []*Rotor {
+	moving := make([]*Rotor, 0, len(rotors))
+	for _, rotor := range rotors {
+		if !rotor.stationary {
+			moving = append(moving, rotor)
+		}
+	}
+	return moving
+}
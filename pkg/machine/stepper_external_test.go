@@ -0,0 +1,73 @@
+package machine_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sudo-sturbia/xenigma/v6/pkg/machine"
+)
+
+// reverseStepper moves rotors like an odometer, starting from the last
+// rotor instead of the first.
+type reverseStepper struct{}
+
+func (reverseStepper) Step(rotors []*machine.Rotor) {
+	for i := len(rotors) - 1; i >= 0; i-- {
+		if rotors[i].Stationary() {
+			continue
+		}
+		rotors[i].TakeStep()
+		if rotors[i].TakenSteps() != 0 { // Rotor didn't complete a cycle.
+			break
+		}
+	}
+}
+
+// TestCustomStepper tests that a stepper written outside of package machine
+// can move a machine's rotors.
+func TestCustomStepper(t *testing.T) {
+	m, err := machine.Read("../../test-data/config-3.json")
+	if err != nil {
+		t.Fatalf("failed to read machine: %v", err)
+	}
+	if err := m.SetStepper(reverseStepper{}); err != nil {
+		t.Fatalf("failed to set stepper: %v", err)
+	}
+
+	before := m.Rotors().Setting()
+	if _, err := m.Encrypt("a"); err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	after := m.Rotors().Setting()
+	last := len(before) - 1
+	for i := range before {
+		if moved := before[i] != after[i]; moved != (i == last) {
+			t.Errorf("rotor %d: incorrect movement, want moved: %t, got: %t", i, i == last, moved)
+		}
+	}
+
+	message := strings.Repeat("Stepping rotors from outside of the package. ", 20)
+	m.Reset()
+	encrypted, err := m.Encrypt(message)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	m.Reset()
+	decrypted, err := m.Encrypt(encrypted)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if want := strings.ToLower(message); decrypted != want {
+		t.Errorf("incorrect decryption, want: %s, got: %s", want, decrypted)
+	}
+
+	m.Reset()
+	parallel, err := m.EncryptParallel([]byte(message), 4)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if string(parallel) != encrypted {
+		t.Errorf("incorrect parallel encryption, want: %s, got: %s", encrypted, parallel)
+	}
+}
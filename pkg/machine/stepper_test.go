@@ -0,0 +1,122 @@
+package machine
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newTestStepperMachine returns a generated machine with notches at "a" and
+// "n" on every rotor, which moves its rotors using the given stepper.
+func newTestStepperMachine(t *testing.T, stepper Stepper) *Machine {
+	t.Helper()
+
+	m := GenerateWithSource(4, rand.NewSource(42))
+	for _, rotor := range m.rotors.rotors {
+		rotor.notches = []int{0, 13}
+	}
+	if err := m.SetStepper(stepper); err != nil {
+		t.Fatalf("failed to set stepper: %v", err)
+	}
+	return m
+}
+
+// TestSteppers tests that messages encrypted using each of the built-in
+// steppers are decrypted, and that seeking and parallel encryption produce
+// the same results as encrypting.
+func TestSteppers(t *testing.T) {
+	prefix := strings.Repeat("Pluggable stepping strategies. ", 50)
+	suffix := strings.Repeat("Stepping as a security parameter. ", 50)
+	letters := uint64(50 * 27) // Number of letters in prefix.
+
+	for _, stepper := range []Stepper{OdometerStepper{}, EnigmaStepper{}, TypexStepper{}, KeystreamStepper{}} {
+		name, _ := stepperName(stepper)
+		m := newTestStepperMachine(t, stepper)
+
+		encrypted, err := m.Encrypt(prefix + suffix)
+		if err != nil {
+			t.Fatalf("%s: failed to encrypt: %v", name, err)
+		}
+
+		m.Reset()
+		decrypted, err := m.Encrypt(encrypted)
+		if err != nil {
+			t.Fatalf("%s: failed to decrypt: %v", name, err)
+		}
+		if want := strings.ToLower(prefix + suffix); decrypted != want {
+			t.Errorf("%s: incorrect decryption, want: %s, got: %s", name, want, decrypted)
+		}
+
+		m.SeekTo(letters)
+		got, err := m.Encrypt(suffix)
+		if err != nil {
+			t.Fatalf("%s: failed to encrypt: %v", name, err)
+		}
+		if want := encrypted[len(prefix):]; got != want {
+			t.Errorf("%s: incorrect encryption after seeking, want: %s, got: %s", name, want, got)
+		}
+
+		m.Reset()
+		parallel, err := m.EncryptParallel([]byte(prefix+suffix), 4)
+		if err != nil {
+			t.Fatalf("%s: failed to encrypt: %v", name, err)
+		}
+		if string(parallel) != encrypted {
+			t.Errorf("%s: parallel encryption differs from sequential encryption", name)
+		}
+	}
+}
+
+// TestTypexStepper tests that rotors moved by a TypexStepper move when the
+// rotor before them moves from a notch, and don't double-step.
+func TestTypexStepper(t *testing.T) {
+	rotors := newRotorArr(t, []int{24, 12, 0}, []int{1, 1, 1}, []int{26, 26, 26})
+	for _, rotor := range rotors {
+		rotor.notches = []int{12, 25}
+	}
+
+	for i, want := range [][]int{
+		{25, 12, 0},
+		{0, 13, 1},
+		{1, 13, 1},
+		{2, 13, 1},
+	} {
+		TypexStepper{}.Step(rotors)
+
+		got := make([]int, len(rotors))
+		for j, rotor := range rotors {
+			got[j] = rotor.position
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("test %d: mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// TestStepperConfig tests reading and writing the stepper of a machine.
+func TestStepperConfig(t *testing.T) {
+	m := newTestStepperMachine(t, KeystreamStepper{})
+	if err := Write(m, "../../test-data/generate/stepper.json"); err != nil {
+		t.Fatalf("failed to write machine: %v", err)
+	}
+
+	r, err := Read("../../test-data/generate/stepper.json")
+	if err != nil {
+		t.Fatalf("failed to read machine: %v", err)
+	}
+	if _, ok := r.Stepper().(KeystreamStepper); !ok {
+		t.Errorf("incorrect stepper, want: KeystreamStepper, got: %T", r.Stepper())
+	}
+
+	if _, err := ParseStepper("sigaba"); err == nil {
+		t.Errorf("parsed unknown stepper")
+	}
+
+	var validation *ValidationError
+	if err := new(Machine).SetStepper(EnigmaStepper{}); !errors.As(err, &validation) || validation.Path != "rotors" {
+		t.Errorf("machine without rotors: want rotors validation error, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package machine
+
+import (
+	"io"
+	"unicode/utf8"
+)
+
+// chunkSize is the size of chunks read by a Reader from its source.
+const chunkSize = 4096
+
+// Writer is an io.WriteCloser that encrypts everything written to it using
+// a Machine, and writes the result to an underlying io.Writer.
+type Writer struct {
+	m        *Machine
+	w        io.Writer
+	opts     EncryptOptions
+	reversed [][]int
+	pending  []byte // Incomplete UTF-8 sequence from the last write.
+	err      error
+}
+
+// Reader is an io.Reader that reads from an underlying io.Reader and
+// returns the encryption of the read contents using a Machine.
+type Reader struct {
+	m        *Machine
+	r        io.Reader
+	opts     EncryptOptions
+	reversed [][]int
+	pending  []byte // Incomplete UTF-8 sequence from the last read.
+	out      []byte // Encrypted bytes not yet returned.
+	err      error
+}
+
+// NewWriter returns a Writer that encrypts written contents using m and
+// writes them to w. Encryption is done incrementally, so a message written
+// in several chunks produces the same result as Encrypt. The Writer uses the
+// options m has when it's created.
+//
+// Close must be called after the last write to flush any buffered data.
+// Close doesn't close w.
+func (m *Machine) NewWriter(w io.Writer) *Writer {
+	s := &Writer{
+		m:    m,
+		w:    w,
+		opts: m.opts,
+		err:  m.Verify(),
+	}
+	if s.err == nil {
+		s.reversed = reverseConnections(m)
+	}
+	return s
+}
+
+// NewReader returns a Reader that reads from r and returns the encryption
+// of read contents using m. Encryption is done incrementally, so reading a
+// message in several chunks produces the same result as Encrypt. The Reader
+// uses the options m has when it's created.
+func (m *Machine) NewReader(r io.Reader) *Reader {
+	s := &Reader{
+		m:    m,
+		r:    r,
+		opts: m.opts,
+		err:  m.Verify(),
+	}
+	if s.err == nil {
+		s.reversed = reverseConnections(m)
+	}
+	return s
+}
+
+// Write encrypts p and writes the result to the underlying writer. The
+// returned count is the number of bytes of p consumed.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	data := append(w.pending, p...)
+	n := fullRunes(data)
+	w.pending = append([]byte(nil), data[n:]...)
+
+	if _, err := w.w.Write(w.m.encryptBytes(data[:n], w.reversed, w.opts)); err != nil {
+		w.err = err
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close flushes any buffered data to the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if len(w.pending) != 0 {
+		_, w.err = w.w.Write(w.m.encryptBytes(w.pending, w.reversed, w.opts))
+		w.pending = nil
+	}
+	return w.err
+}
+
+// Read reads from the underlying reader and places up to len(p) encrypted
+// bytes into p.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		chunk := make([]byte, chunkSize)
+		n, err := r.r.Read(chunk)
+
+		data := append(r.pending, chunk[:n]...)
+		full := len(data)
+		if err == nil {
+			full = fullRunes(data)
+		}
+		r.pending = append([]byte(nil), data[full:]...)
+		r.out = r.m.encryptBytes(data[:full], r.reversed, r.opts)
+		r.err = err
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// fullRunes returns the length of the longest prefix of p that doesn't end
+// with an incomplete UTF-8 sequence.
+func fullRunes(p []byte) int {
+	for i := len(p) - 1; i >= 0 && i >= len(p)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(p[i]) {
+			if utf8.FullRune(p[i:]) {
+				return len(p)
+			}
+			return i
+		}
+	}
+	return len(p)
+}
@@ -0,0 +1,108 @@
+package machine
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// streamMessages are messages used to compare streaming with Encrypt.
+var streamMessages = []string{
+	"Hello, world!",
+	"This is an encryption example using a xenigma machine.\n" +
+		"Encrypted messages can also be decrypted using the same machine.",
+	"Multi-byte runes: ünïcödé, ß, 日本語.",
+}
+
+// TestWriter compares the output of a Writer, written in chunks of different
+// sizes, with Encrypt.
+func TestWriter(t *testing.T) {
+	for i, message := range streamMessages {
+		for _, size := range []int{1, 2, 3, 7, len(message)} {
+			want := encryptWith(t, "../../test-data/config-1.json", message)
+
+			m, err := Read("../../test-data/config-1.json")
+			if err != nil {
+				t.Fatalf("failed to read machine: %v", err)
+			}
+
+			buffer := new(bytes.Buffer)
+			w := m.NewWriter(buffer)
+			for j := 0; j < len(message); j += size {
+				end := j + size
+				if end > len(message) {
+					end = len(message)
+				}
+				if _, err := w.Write([]byte(message[j:end])); err != nil {
+					t.Fatalf("test %d: failed to write: %v", i, err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("test %d: failed to close: %v", i, err)
+			}
+
+			if got := buffer.String(); got != want {
+				t.Errorf("test %d, size %d: want %q, got %q", i, size, want, got)
+			}
+		}
+	}
+}
+
+// TestReader compares the output of a Reader, reading from sources of
+// different behaviour, with Encrypt.
+func TestReader(t *testing.T) {
+	for i, message := range streamMessages {
+		for j, source := range []func(io.Reader) io.Reader{
+			func(r io.Reader) io.Reader { return r },
+			iotest.OneByteReader,
+			iotest.HalfReader,
+			iotest.DataErrReader,
+		} {
+			want := encryptWith(t, "../../test-data/config-1.json", message)
+
+			m, err := Read("../../test-data/config-1.json")
+			if err != nil {
+				t.Fatalf("failed to read machine: %v", err)
+			}
+
+			got, err := ioutil.ReadAll(iotest.OneByteReader(m.NewReader(source(strings.NewReader(message)))))
+			if err != nil {
+				t.Fatalf("test %d, source %d: failed to read: %v", i, j, err)
+			}
+
+			if string(got) != want {
+				t.Errorf("test %d, source %d: want %q, got %q", i, j, want, string(got))
+			}
+		}
+	}
+}
+
+// TestStreamInvalid tests that streaming using an invalid machine fails.
+func TestStreamInvalid(t *testing.T) {
+	m := new(Machine)
+
+	if _, err := m.NewWriter(ioutil.Discard).Write([]byte("Hello")); err == nil {
+		t.Errorf("writer: want error, got nil")
+	}
+	if _, err := m.NewReader(strings.NewReader("Hello")).Read(make([]byte, 5)); err == nil {
+		t.Errorf("reader: want error, got nil")
+	}
+}
+
+// encryptWith encrypts a message using Encrypt and the machine at path.
+func encryptWith(t *testing.T, path, message string) string {
+	t.Helper()
+	m, err := Read(path)
+	if err != nil {
+		t.Fatalf("failed to read machine: %v", err)
+	}
+
+	encrypted, err := m.Encrypt(message)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	return encrypted
+}